@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/config"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/json"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/toml"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/yaml"
+	"github.com/spf13/cobra"
+)
+
+// loadConfig picks the file reader according to the file type and reads
+// the instructions out of the configuration file. Without a file the
+// configuration is discovered from the working directory up
+func loadConfig(file string) (*cmd.FileReaderContext, *cmd.Structure, error) {
+	if file == "" {
+		discovered, err := config.Discover(".")
+		if err != nil { return nil, nil, err }
+		file = discovered
+	}
+
+	// generating absolute path for the configuration file
+	filePath, err := filepath.Abs(file)
+	if err != nil { return nil, nil, fmt.Errorf("Could not get absolute path") }
+
+	// setting file context to read file and checking for file type,
+	// a directory or a glob is read with the type of its first file
+	fileContext := &cmd.FileReaderContext{}
+	formatFile := filePath
+	if config.IsPattern(filePath) {
+		files, err := config.Expand(filePath)
+		if err != nil { return nil, nil, err }
+		formatFile = files[0]
+	}
+
+	// choosing the file reader according to file type
+	switch strings.ToLower(filepath.Ext(formatFile)) {
+	case ".json": fileContext.SetStrategy(&json.Reader{})
+	case ".jsonc", ".json5": fileContext.SetStrategy(&json.Reader{Relaxed: true})
+	case ".yaml", ".yml": fileContext.SetStrategy(&yaml.Reader{})
+	case ".toml": fileContext.SetStrategy(&toml.Reader{})
+	default: return nil, nil, fmt.Errorf("Invalid file format, use json, jsonc, json5, yaml, yml or toml")
+	}
+
+	// calling the instructions reader
+	fileContents, err := fileContext.ReadInstructions(filePath)
+	if err != nil { return nil, nil, err }
+
+	// files of request bodies are read relative to the configuration
+	fileContents.ConfigDir = filepath.Dir(formatFile)
+
+	return fileContext, fileContents, nil
+}
+
+// loadEnvironment reads the configuration file and activates the selected environment
+func loadEnvironment(global *globalOptions) (*cmd.FileReaderContext, *cmd.Structure, error) {
+	fileContext, fileContents, err := loadConfig(global.file)
+	if err != nil { return nil, nil, err }
+
+	if err := fileContents.SelectEnvironment(global.env); err != nil { return nil, nil, err }
+	return fileContext, fileContents, nil
+}
+
+// pipelineCompletion suggests the pipelines defined in the configuration file
+func pipelineCompletion(global *globalOptions) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		_, fileContents, err := loadConfig(global.file)
+		if err != nil { return nil, cobra.ShellCompDirectiveNoFileComp }
+
+		return append(fileContents.PipelineNames(), "all"), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// stepCompletion suggests the step names defined in the configuration file
+func stepCompletion(global *globalOptions) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		_, fileContents, err := loadConfig(global.file)
+		if err != nil { return nil, cobra.ShellCompDirectiveNoFileComp }
+
+		names := []string{}
+		for _, pipeline := range fileContents.PipelineNames() {
+			for _, step := range fileContents.Pipeline(pipeline) {
+				if step.Name != "" { names = append(names, step.Name) }
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
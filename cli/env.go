@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+func newEnvCommand(global *globalOptions) *cobra.Command {
+	list := func(c *cobra.Command, args []string) error {
+		_, fileContents, err := loadConfig(global.file)
+		if err != nil { return err }
+
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Environment", "Base URL", "Selected"})
+		for _, env := range cmd.EnvironmentNames {
+			url, _ := fileContents.BaseURL.URL(env)
+			selected := ""
+			if env == global.env { selected = "*" }
+			t.AppendRow(table.Row{env, url, selected})
+		}
+		t.Render()
+
+		return nil
+	}
+
+	command := &cobra.Command{
+		Use: "env",
+		Short: "List the environments defined in the configuration file",
+		Args: cobra.NoArgs,
+		RunE: list,
+	}
+	command.AddCommand(&cobra.Command{
+		Use: "list",
+		Short: "List the environments and their base urls",
+		Args: cobra.NoArgs,
+		RunE: list,
+	})
+
+	return command
+}
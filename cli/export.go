@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/runner"
+	"github.com/spf13/cobra"
+)
+
+func newExportCommand(global *globalOptions) *cobra.Command {
+	format, out, force := "", "", false
+
+	command := &cobra.Command{
+		Use: "export",
+		Short: "Convert the configuration file to json, yaml, toml or curl commands",
+		Example: "  apee-i export --format yaml --out api.yaml\n  apee-i export --format curl --env staging",
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			_, fileContents, err := loadEnvironment(global)
+			if err != nil { return err }
+
+			if format == "" { format = formatOf(out) }
+
+			var data []byte
+			if format == "curl" { data = []byte(curlCommands(fileContents))
+			} else if data, err = encodeConfig(fileContents, format); err != nil { return err }
+
+			if out == "" { _, err = os.Stdout.Write(data); return err }
+			if _, err := os.Stat(out); err == nil && !force { return fmt.Errorf("%s already exists, use --force to overwrite it", out) }
+			return os.WriteFile(out, data, 0644)
+		},
+	}
+	command.Flags().StringVar(&format, "format", "", "output format (json/yaml/toml/curl), guessed from --out when empty")
+	command.Flags().StringVarP(&out, "out", "o", "", "file to write to instead of stdout")
+	command.Flags().BoolVar(&force, "force", false, "overwrite the output file if it already exists")
+	command.RegisterFlagCompletionFunc("format", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "yaml", "toml", "curl"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return command
+}
+
+// curlCommands writes every step of every pipeline as a curl command
+// against the active environment
+func curlCommands(fileContents *cmd.Structure) string {
+	builder := &strings.Builder{}
+	defaults := fileContents.ActiveDefaults()
+
+	for _, pipeline := range fileContents.PipelineNames() {
+		steps := fileContents.Pipeline(pipeline)
+		if len(steps) == 0 { continue }
+
+		fmt.Fprintf(builder, "# %s\n", pipeline)
+		for _, step := range steps {
+			if step.Kind == "grpc" { grpcurlCommand(builder, fileContents, step); continue }
+			if step.Kind == "websocket" { fmt.Fprintf(builder, "# websocket %s cannot be written as a curl command\n", fileContents.ActiveURL + step.Endpoint); continue }
+
+			method := step.Method
+			if method == "" && step.GraphQL != nil { method = "POST" }
+			if method == "" { method = "GET" }
+
+			address, err := runner.WithQuery(fileContents.ActiveURL + step.Endpoint, defaults.Query, step.Query)
+			if err != nil { address = fileContents.ActiveURL + step.Endpoint }
+			fmt.Fprintf(builder, "curl -X %s %s", method, shellQuote(address))
+			curlTransport(builder, fileContents)
+			if step.Kind == "sse" { builder.WriteString(" -N \\\n  -H 'Accept: text/event-stream'") }
+
+			// default headers sit beneath the ones of the step
+			headers := map[string]any{}
+			for key, value := range defaults.Headers { headers[key] = value }
+			if custom, ok := step.Headers.(map[string]any); ok {
+				for key, value := range custom { headers[key] = value }
+			}
+			keys := make([]string, 0, len(headers))
+			for key := range headers { keys = append(keys, key) }
+			sort.Strings(keys)
+			for _, key := range keys { fmt.Fprintf(builder, " \\\n  -H %s", shellQuote(fmt.Sprintf("%s: %v", key, headers[key]))) }
+			curlBody(builder, step)
+			builder.WriteString("\n")
+		}
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+// curlTransport writes the flags for the unix socket, the proxy and the
+// HTTP version of the active environment
+func curlTransport(builder *strings.Builder, fileContents *cmd.Structure) {
+	options := fileContents.ActiveTransport()
+	if fileContents.ActiveSocket != "" { fmt.Fprintf(builder, " \\\n  --unix-socket %s", shellQuote(fileContents.ActiveSocket)) }
+	if options.Proxy != "" { fmt.Fprintf(builder, " \\\n  -x %s", shellQuote(options.Proxy)) }
+
+	switch {
+	case options.Protocol == "http1.1": builder.WriteString(" --http1.1")
+	case options.Protocol == "http2" && strings.HasPrefix(fileContents.ActiveURL, "https"): builder.WriteString(" --http2")
+	case options.Protocol == "http2": builder.WriteString(" --http2-prior-knowledge")
+	}
+}
+
+// curlBody writes the flags sending the body of a step with its body type
+func curlBody(builder *strings.Builder, step cmd.PipelineBody) {
+	if step.GraphQL != nil { step.Body, step.BodyType = step.GraphQL.Payload(nil), "json" }
+	if step.Body == nil || step.BodyType == "none" { return }
+
+	fields, _ := step.Body.(map[string]any)
+	keys := make([]string, 0, len(fields))
+	for key := range fields { keys = append(keys, key) }
+	sort.Strings(keys)
+
+	switch step.BodyType {
+	case "form":
+		for _, key := range keys {
+			for _, value := range repeated(fields[key]) { fmt.Fprintf(builder, " \\\n  --data-urlencode %s", shellQuote(fmt.Sprintf("%s=%v", key, value))) }
+		}
+	case "multipart":
+		for _, key := range keys {
+			for _, value := range repeated(fields[key]) {
+				part := fmt.Sprint(value)
+				if path, isFile := cmd.FileReference(value); isFile { part = "@" + path }
+				fmt.Fprintf(builder, " \\\n  -F %s", shellQuote(key + "=" + part))
+			}
+		}
+	case "raw", "binary":
+		if step.BodyType == "binary" { builder.WriteString(" \\\n  -H 'Content-Type: application/octet-stream'") }
+		if path, isFile := cmd.FileReference(step.Body); isFile {
+			fmt.Fprintf(builder, " \\\n  --data-binary %s", shellQuote("@" + path))
+		} else {
+			fmt.Fprintf(builder, " \\\n  --data-binary %s", shellQuote(fmt.Sprint(step.Body)))
+		}
+	default:
+		body, _ := json.Marshal(step.Body)
+		fmt.Fprintf(builder, " \\\n  -H 'Content-Type: application/json' \\\n  -d %s", shellQuote(string(body)))
+	}
+}
+
+// grpcurlCommand writes a gRPC step as a grpcurl command
+func grpcurlCommand(builder *strings.Builder, fileContents *cmd.Structure, step cmd.PipelineBody) {
+	address, secure := step.GRPC.Address, false
+	if parsed, err := url.Parse(fileContents.ActiveURL); err == nil && parsed.Host != "" {
+		secure = parsed.Scheme == "https" || parsed.Scheme == "grpcs"
+		if address == "" { address = parsed.Host }
+	}
+
+	builder.WriteString("grpcurl")
+	if !secure { builder.WriteString(" -plaintext") }
+	for _, path := range step.GRPC.ImportPaths { fmt.Fprintf(builder, " -import-path %s", shellQuote(path)) }
+	for _, proto := range step.GRPC.Protos { fmt.Fprintf(builder, " -proto %s", shellQuote(proto)) }
+	if step.GRPC.Message != nil {
+		message, _ := json.Marshal(step.GRPC.Message)
+		fmt.Fprintf(builder, " \\\n  -d %s", shellQuote(string(message)))
+	}
+	fmt.Fprintf(builder, " \\\n  %s %s\n", shellQuote(address), shellQuote(step.GRPC.Service + "/" + step.GRPC.Method))
+}
+
+// repeated lists the values of a field, a list repeats the field once per item
+func repeated(value any) []any {
+	if list, ok := value.([]any); ok { return list }
+	return []any{value}
+}
+
+// shellQuote wraps a value in single quotes for posix shells
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
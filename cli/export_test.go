@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+func TestCurlCommands(t *testing.T) {
+	fileContents := &cmd.Structure{
+		BaseURL: cmd.Environments{Development: "https://api.example.com"},
+		Defaults: cmd.Defaults{Headers: map[string]string{"Accept": "application/json"}, Query: map[string]any{"lang": "en"}},
+		PipelineBody: []cmd.PipelineBody{
+			{Endpoint: "/users", Query: map[string]any{"tag": []any{"a", "b"}}, Headers: map[string]any{"X-Name": "O'Brien"}},
+			{Endpoint: "/users", Method: "POST", Body: map[string]any{"name": "Jane"}},
+			{Endpoint: "/login", Method: "POST", BodyType: "form", Body: map[string]any{"user": "jane", "scope": []any{"read", "write"}}},
+			{Endpoint: "/avatar", Method: "PUT", BodyType: "multipart", Body: map[string]any{"file": map[string]any{"file": "avatar.png"}, "alt": "me"}},
+			{Endpoint: "/upload", Method: "PUT", BodyType: "binary", Body: map[string]any{"file": "data.bin"}},
+			{Endpoint: "/graphql", GraphQL: &cmd.GraphQL{Query: "{ me { id } }"}},
+			{Kind: "sse", Endpoint: "/events"},
+			{Kind: "websocket", Endpoint: "/ws"},
+		},
+		CustomPipelines: map[string]cmd.Pipeline{
+			"grpc": {Steps: []cmd.PipelineBody{
+				{Kind: "grpc", GRPC: &cmd.GRPC{Service: "users.Users", Method: "Get", Protos: []string{"users.proto"}, Message: map[string]any{"id": float64(1)}}},
+			}},
+		},
+	}
+	if err := fileContents.SelectEnvironment("development"); err != nil { t.Fatal(err) }
+
+	want := `# current
+curl -X GET 'https://api.example.com/users?lang=en&tag=a&tag=b' \
+  -H 'Accept: application/json' \
+  -H 'X-Name: O'\''Brien'
+curl -X POST 'https://api.example.com/users?lang=en' \
+  -H 'Accept: application/json' \
+  -H 'Content-Type: application/json' \
+  -d '{"name":"Jane"}'
+curl -X POST 'https://api.example.com/login?lang=en' \
+  -H 'Accept: application/json' \
+  --data-urlencode 'scope=read' \
+  --data-urlencode 'scope=write' \
+  --data-urlencode 'user=jane'
+curl -X PUT 'https://api.example.com/avatar?lang=en' \
+  -H 'Accept: application/json' \
+  -F 'alt=me' \
+  -F 'file=@avatar.png'
+curl -X PUT 'https://api.example.com/upload?lang=en' \
+  -H 'Accept: application/json' \
+  -H 'Content-Type: application/octet-stream' \
+  --data-binary '@data.bin'
+curl -X POST 'https://api.example.com/graphql?lang=en' \
+  -H 'Accept: application/json' \
+  -H 'Content-Type: application/json' \
+  -d '{"query":"{ me { id } }"}'
+curl -X GET 'https://api.example.com/events?lang=en' -N \
+  -H 'Accept: text/event-stream' \
+  -H 'Accept: application/json'
+# websocket https://api.example.com/ws cannot be written as a curl command
+
+# grpc
+grpcurl -proto 'users.proto' \
+  -d '{"id":1}' \
+  'api.example.com' 'users.Users/Get'
+
+`
+	if got := curlCommands(fileContents); got != want { t.Errorf("curlCommands() =\n%s\nwant\n%s", got, want) }
+}
+
+func TestCurlTransport(t *testing.T) {
+	fileContents := &cmd.Structure{
+		BaseURL: cmd.Environments{Staging: "unix:///run/api.sock/v1"},
+		Environments: cmd.EnvironmentSettings{Staging: cmd.Settings{Transport: cmd.TransportSettings{Proxy: "http://proxy:3128", Protocol: "http2"}}},
+		PipelineBody: []cmd.PipelineBody{{Endpoint: "/health"}},
+	}
+	if err := fileContents.SelectEnvironment("staging"); err != nil { t.Fatal(err) }
+
+	want := "curl -X GET 'http://localhost/v1/health' \\\n  --unix-socket '/run/api.sock' \\\n  -x 'http://proxy:3128' --http2-prior-knowledge\n"
+	if got := curlCommands(fileContents); !strings.Contains(got, want) { t.Errorf("curlCommands() =\n%s\nwant it to hold\n%s", got, want) }
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"gopkg.in/yaml.v3"
+)
+
+// encodeConfig turns a structure into json or yaml according to the format
+func encodeConfig(fileContents *cmd.Structure, format string) ([]byte, error) {
+	switch format {
+	case "yaml", "yml":
+		buffer := &bytes.Buffer{}
+		encoder := yaml.NewEncoder(buffer)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(fileContents); err != nil { return nil, err }
+		return buffer.Bytes(), nil
+	case "json", "jsonc", "json5":
+		data, err := json.MarshalIndent(fileContents, "", "\t")
+		if err != nil { return nil, err }
+		return append(data, '\n'), nil
+	case "toml":
+		// going through json so the json names of the keys are used
+		data, err := json.Marshal(fileContents)
+		if err != nil { return nil, err }
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		document := map[string]any{}
+		if err := decoder.Decode(&document); err != nil { return nil, err }
+
+		buffer := &bytes.Buffer{}
+		if err := toml.NewEncoder(buffer).Encode(tomlNumbers(document)); err != nil { return nil, err }
+		return buffer.Bytes(), nil
+	}
+	return nil, fmt.Errorf("Invalid file format, use json, yaml or toml")
+}
+
+// tomlNumbers turns the numbers read back from json into integers when they
+// are whole so toml does not write a status code as 201.0
+func tomlNumbers(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, item := range value { value[key] = tomlNumbers(item) }
+	case []any:
+		for i, item := range value { value[i] = tomlNumbers(item) }
+	case json.Number:
+		if number, err := value.Int64(); err == nil { return number }
+		number, _ := value.Float64()
+		return number
+	}
+	return value
+}
+
+// formatOf tells the configuration format out of a file name
+func formatOf(file string) string {
+	ext := filepath.Ext(file)
+	if ext == "" { return "" }
+	return ext[1:]
+}
+
+// writeConfig stores a structure in a json or yaml file, an existing
+// file is only replaced when force is set
+func writeConfig(file string, fileContents *cmd.Structure, force bool) error {
+	if _, err := os.Stat(file); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", file)
+	}
+
+	data, err := encodeConfig(fileContents, formatOf(file))
+	if err != nil { return err }
+
+	return os.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncodeTOML(t *testing.T) {
+	fileContents := (&initOptions{development: "http://dev", auth: "JWT", loginRoute: "/login", tokenLocation: "data.token"}).config()
+	fileContents.Variables = map[string]any{"price": 1.5, "count": float64(3)}
+
+	data, err := encodeConfig(fileContents, "toml")
+	if err != nil { t.Fatal(err) }
+
+	// whole numbers are integers, others stay floats
+	for _, line := range []string{"expectedStatusCode = 201\n", "count = 3\n", "price = 1.5\n"} {
+		if !strings.Contains(string(data), line) { t.Errorf("encodeConfig() wrote\n%s\nwithout %q", data, line) }
+	}
+
+	// the file reads back with the status code the scaffold asked for
+	file := filepath.Join(t.TempDir(), "api.toml")
+	if err := writeConfig(file, fileContents, false); err != nil { t.Fatal(err) }
+	_, loaded, err := loadConfig(file)
+	if err != nil { t.Fatalf("the encoded toml does not load: %v", err) }
+	if got := loaded.CustomPipelines["users"].Steps[1].ExpectedStatusCode; got != 201 { t.Errorf("the encoded toml expects status %d, want 201", got) }
+}
+
+func TestEncodeEmptyBlocks(t *testing.T) {
+	fileContents := (&initOptions{development: "http://dev", auth: "none"}).config()
+
+	// blocks the wizard does not set are not written as empty ones
+	for format, empty := range map[string][]string{
+		"json": {`"defaults"`, `"environments"`, `"cookieJar"`, `"baseline"`},
+		"toml": {"[defaults]", "[environments", "[cookieJar]", "[baseline]"},
+		"yaml": {"defaults:", "environments:", "cookieJar:", "baseline:"},
+	} {
+		data, err := encodeConfig(fileContents, format)
+		if err != nil { t.Fatal(err) }
+		for _, block := range empty {
+			if strings.Contains(string(data), block) { t.Errorf("encodeConfig() in %s wrote %s\n%s", format, block, data) }
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// sparks draw the trend of a step, from its fastest run to its slowest
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+func newHistoryCommand(global *globalOptions) *cobra.Command {
+	last := 0
+	pipeline := ""
+
+	command := &cobra.Command{
+		Use: "history [step]",
+		Short: "Show how the timings of the steps evolved across runs",
+		Long: "History lists the steps kept in " + cmd.HistoryFile + ", next to the configuration, with the trend of their latest runs.\n" +
+			"Given a step name or endpoint, it lists each of its runs instead",
+		Example: "  apee-i history\n  apee-i history getUser --env staging\n  apee-i history /users --last 20",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			_, fileContents, err := loadConfig(global.file)
+			if err != nil { return err }
+			path := historyPath(fileContents)
+			history, err := cmd.ReadHistory(path)
+			if err != nil { return err }
+
+			// keeping the entries of the selected environment, pipeline and step
+			entries := []cmd.HistoryEntry{}
+			for _, entry := range history {
+				if c.Flags().Changed("env") && entry.Environment != global.env { continue }
+				if pipeline != "" && entry.Pipeline != pipeline { continue }
+				if len(args) == 1 && entry.Step != args[0] && entry.Endpoint != args[0] { continue }
+				entries = append(entries, entry)
+			}
+			if len(entries) == 0 { return fmt.Errorf("No runs are kept in %s for the given filters yet", path) }
+
+			if len(args) == 1 { stepRuns(entries, last) } else { stepTrends(entries, last) }
+			return nil
+		},
+	}
+	command.Flags().IntVar(&last, "last", 10, "number of latest runs shown for every step")
+	command.Flags().StringVarP(&pipeline, "pipeline", "p", "", "show only the steps of this pipeline")
+
+	return command
+}
+
+// stepTrends prints a line per step with the trend of its latest runs
+func stepTrends(entries []cmd.HistoryEntry, last int) {
+	keys, runs := groupRuns(entries, last)
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Environment", "Pipeline", "Row", "Step", "Runs", "Last", "Median", "Trend"})
+	t.AppendSeparator()
+	for _, key := range keys {
+		latencies := latenciesOf(runs[key])
+		latest := runs[key][len(runs[key]) - 1]
+		t.AppendRow(table.Row{latest.Environment, latest.Pipeline, latest.Iteration, latest.Step, len(latencies), latest.Latency().String(), cmd.Median(latencies).String(), sparkline(latencies)})
+	}
+	t.Render()
+}
+
+// stepRuns prints every latest run of the selected steps
+func stepRuns(entries []cmd.HistoryEntry, last int) {
+	keys, runs := groupRuns(entries, last)
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Run", "Environment", "Pipeline", "Row", "Step", "Result", "Time Lapsed", "TTFB", "vs Median"})
+	t.AppendSeparator()
+	for _, key := range keys {
+		median := cmd.Median(latenciesOf(runs[key]))
+		for _, entry := range runs[key] {
+			result := "passed"
+			if !entry.Passed { result = "failed" }
+
+			change := ""
+			if median > 0 { change = fmt.Sprintf("%+.0f%%", float64(entry.Latency() - median) / float64(median) * 100) }
+			t.AppendRow(table.Row{entry.Run.Local().Format(time.DateTime), entry.Environment, entry.Pipeline, entry.Iteration, entry.Step, result, entry.Latency().String(), entry.TTFB.String(), change})
+		}
+		t.AppendSeparator()
+	}
+	t.Render()
+}
+
+// groupRuns splits the entries per step, in the order the steps first
+// appear, keeping the last runs of each
+func groupRuns(entries []cmd.HistoryEntry, last int) ([]string, map[string][]cmd.HistoryEntry) {
+	keys, runs := []string{}, map[string][]cmd.HistoryEntry{}
+	for _, entry := range entries {
+		if _, exists := runs[entry.Key()]; !exists { keys = append(keys, entry.Key()) }
+		runs[entry.Key()] = append(runs[entry.Key()], entry)
+	}
+
+	for key, list := range runs {
+		if last > 0 && len(list) > last { runs[key] = list[len(list) - last:] }
+	}
+	return keys, runs
+}
+
+// latenciesOf lists the latencies of some runs
+func latenciesOf(entries []cmd.HistoryEntry) []time.Duration {
+	latencies := make([]time.Duration, len(entries))
+	for i, entry := range entries { latencies[i] = entry.Latency() }
+	return latencies
+}
+
+// historyPath is the HistoryFile of the configuration
+func historyPath(fileContents *cmd.Structure) string {
+	return filepath.Join(fileContents.ConfigDir, cmd.HistoryFile)
+}
+
+// sparkline draws durations as bars between the shortest and the longest one
+func sparkline(durations []time.Duration) string {
+	if len(durations) == 0 { return "" }
+	low, high := durations[0], durations[0]
+	for _, duration := range durations {
+		if duration < low { low = duration }
+		if duration > high { high = duration }
+	}
+
+	line := strings.Builder{}
+	for _, duration := range durations {
+		level := 0
+		if high > low { level = int(float64(duration - low) / float64(high - low) * float64(len(sparks) - 1)) }
+		line.WriteRune(sparks[level])
+	}
+	return line.String()
+}
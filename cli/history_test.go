@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+func TestSparkline(t *testing.T) {
+	ms := time.Millisecond
+	for want, durations := range map[string][]time.Duration{
+		"": nil,
+		"▁": {5 * ms},
+		"▁▁▁": {5 * ms, 5 * ms, 5 * ms},
+		"▁▄█": {10 * ms, 15 * ms, 20 * ms},
+		"█▁▂": {80 * ms, 10 * ms, 20 * ms},
+	} {
+		if got := sparkline(durations); got != want { t.Errorf("sparkline(%v) = %q, want %q", durations, got, want) }
+	}
+}
+
+func TestHistoryNextToConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "api.yaml")
+	if err := writeConfig(file, (&initOptions{development: "http://dev", auth: "none"}).config(), false); err != nil { t.Fatal(err) }
+	global := &globalOptions{file: file, env: "development"}
+
+	// nothing is kept before the first run
+	err := newHistoryCommand(global).RunE(newHistoryCommand(global), nil)
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(dir, cmd.HistoryFile)) { t.Errorf("history before any run returned %v", err) }
+
+	_, fileContents, err := loadEnvironment(global)
+	if err != nil { t.Fatal(err) }
+	fileContents.Results = []cmd.StepResult{{Pipeline: "current", Step: "me", Duration: 20 * time.Millisecond}}
+	for i := 0; i < 2; i++ {
+		if _, err := (&runOptions{}).history(fileContents, time.Now()); err != nil { t.Fatal(err) }
+	}
+
+	// the runs are kept next to the configuration, whatever the working directory
+	history, err := cmd.ReadHistory(filepath.Join(dir, cmd.HistoryFile))
+	if err != nil || len(history) != 2 { t.Fatalf("the history next to the configuration holds %d runs, %v", len(history), err) }
+	if _, err := os.Stat(cmd.HistoryFile); err == nil { t.Errorf("the history was written to the working directory") }
+
+	if err := newHistoryCommand(global).RunE(newHistoryCommand(global), []string{"me"}); err != nil { t.Errorf("history of a step returned %v", err) }
+
+	// a run left out of the history does not add to it
+	if _, err := (&runOptions{noHistory: true}).history(fileContents, time.Now()); err != nil { t.Fatal(err) }
+	if history, _ := cmd.ReadHistory(filepath.Join(dir, cmd.HistoryFile)); len(history) != 2 { t.Errorf("--no-history added to the history, which holds %d runs", len(history)) }
+}
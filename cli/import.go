@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+	"github.com/spf13/cobra"
+)
+
+// postmanItem is either a request or a folder of a postman v2 collection
+type postmanItem struct {
+	Name string `json:"name"`
+	Item []postmanItem `json:"item"`
+	Request *struct {
+		Method string `json:"method"`
+		URL any `json:"url"`
+		Header []struct {
+			Key string `json:"key"`
+			Value string `json:"value"`
+			Disabled bool `json:"disabled"`
+		} `json:"header"`
+		Body *struct {
+			Mode string `json:"mode"`
+			Raw string `json:"raw"`
+			URLEncoded []postmanField `json:"urlencoded"`
+			FormData []postmanField `json:"formdata"`
+		} `json:"body"`
+	} `json:"request"`
+}
+
+// postmanField is a field of an urlencoded or form-data postman body
+type postmanField struct {
+	Key string `json:"key"`
+	Value string `json:"value"`
+	Type string `json:"type"`
+	Src any `json:"src"`
+	Disabled bool `json:"disabled"`
+}
+
+// postmanVariable matches a leading `{{baseUrl}}` like variable of a postman url
+var postmanVariable = regexp.MustCompile(`^{{[^}]+}}`)
+
+func newImportCommand() *cobra.Command {
+	out, force := "api.json", false
+
+	command := &cobra.Command{
+		Use: "import <collection.json>",
+		Short: "Create a configuration file out of a Postman collection",
+		Long: "Import converts a Postman v2 collection: top level requests become the current pipeline\n" +
+			"and every folder becomes a custom pipeline",
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil { return fmt.Errorf("Could not open file") }
+
+			collection := postmanItem{}
+			if err := json.Unmarshal(data, &collection); err != nil { return fmt.Errorf("Could not parse collection: %s", err.Error()) }
+
+			fileContents := &cmd.Structure{CustomPipelines: map[string]cmd.Pipeline{}}
+			for _, item := range collection.Item {
+				if item.Request != nil {
+					fileContents.PipelineBody = append(fileContents.PipelineBody, importRequest(item, fileContents))
+					continue
+				}
+				fileContents.CustomPipelines[item.Name] = cmd.Pipeline{Steps: importFolder(item, fileContents)}
+			}
+
+			if err := writeConfig(out, fileContents, force); err != nil { return err }
+
+			fmt.Println(utils.Green + "Imported " + args[0] + " into " + out + utils.Reset)
+			return nil
+		},
+	}
+	command.Flags().StringVarP(&out, "out", "o", out, "configuration file to create (json/yaml)")
+	command.Flags().BoolVar(&force, "force", false, "overwrite the output file if it already exists")
+
+	return command
+}
+
+// importFolder flattens a folder and its sub folders into a list of steps
+func importFolder(folder postmanItem, fileContents *cmd.Structure) []cmd.PipelineBody {
+	steps := []cmd.PipelineBody{}
+	for _, item := range folder.Item {
+		if item.Request != nil { steps = append(steps, importRequest(item, fileContents))
+		} else { steps = append(steps, importFolder(item, fileContents)...) }
+	}
+	return steps
+}
+
+// importRequest converts a single postman request into a step
+func importRequest(item postmanItem, fileContents *cmd.Structure) cmd.PipelineBody {
+	step := cmd.PipelineBody{Name: item.Name, Method: strings.ToUpper(item.Request.Method)}
+	if step.Method == "GET" { step.Method = "" }
+
+	// postman stores the url either as a string or as an object with a raw field
+	raw := ""
+	switch value := item.Request.URL.(type) {
+	case string: raw = value
+	case map[string]any: raw, _ = value["raw"].(string)
+	}
+
+	// splitting the base url from the endpoint
+	if prefix := postmanVariable.FindString(raw); prefix != "" {
+		step.Endpoint = strings.TrimPrefix(raw, prefix)
+	} else if parsed, err := url.Parse(raw); err == nil && parsed.Host != "" {
+		if fileContents.BaseURL.Development == "" { fileContents.BaseURL.Development = parsed.Scheme + "://" + parsed.Host }
+		step.Endpoint = strings.TrimPrefix(raw, parsed.Scheme + "://" + parsed.Host)
+	} else {
+		step.Endpoint = raw
+	}
+
+	headers := map[string]any{}
+	for _, header := range item.Request.Header {
+		if !header.Disabled && !strings.EqualFold(header.Key, "Content-Type") { headers[header.Key] = header.Value }
+	}
+	if len(headers) > 0 { step.Headers = headers }
+
+	if item.Request.Body == nil { return step }
+	switch item.Request.Body.Mode {
+	case "raw":
+		// keeping json bodies as objects so they can be edited and interpolated
+		if item.Request.Body.Raw == "" { break }
+		var body any
+		if err := json.Unmarshal([]byte(item.Request.Body.Raw), &body); err == nil { step.Body = body
+		} else { step.Body, step.BodyType = item.Request.Body.Raw, "raw" }
+	case "urlencoded":
+		step.Body, step.BodyType = importFields(item.Request.Body.URLEncoded), "form"
+	case "formdata":
+		step.Body, step.BodyType = importFields(item.Request.Body.FormData), "multipart"
+	}
+
+	return step
+}
+
+// importFields converts the enabled fields of a postman body, file fields
+// become {file: path} references
+func importFields(fields []postmanField) map[string]any {
+	body := map[string]any{}
+	for _, field := range fields {
+		if field.Disabled { continue }
+		if field.Type == "file" {
+			// postman stores a single file as a string and several as a list
+			src := field.Src
+			if list, ok := src.([]any); ok && len(list) > 0 { src = list[0] }
+			body[field.Key] = map[string]any{"file": fmt.Sprint(src)}
+			continue
+		}
+		body[field.Key] = field.Value
+	}
+	return body
+}
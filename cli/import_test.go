@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// collection is a postman collection with a request at the top level and a
+// folder nesting another one
+const collection = `{
+  "info": {"name": "shop"},
+  "item": [
+    {
+      "name": "List users",
+      "request": {
+        "method": "get",
+        "url": {"raw": "https://shop.example.com/users?page=1"},
+        "header": [
+          {"key": "Content-Type", "value": "application/json"},
+          {"key": "X-Debug", "value": "1", "disabled": true},
+          {"key": "X-Team", "value": "core"}
+        ]
+      }
+    },
+    {
+      "name": "Orders",
+      "item": [
+        {"name": "Create order", "request": {"method": "POST", "url": "{{baseUrl}}/orders", "body": {"mode": "raw", "raw": "{\"items\": [1, 2]}"}}},
+        {
+          "name": "Attachments",
+          "item": [
+            {"name": "Upload", "request": {"method": "POST", "url": "{{baseUrl}}/files", "body": {"mode": "formdata", "formdata": [
+              {"key": "file", "type": "file", "src": ["/tmp/invoice.pdf"]},
+              {"key": "note", "value": "paid", "type": "text"},
+              {"key": "draft", "value": "1", "type": "text", "disabled": true}
+            ]}}},
+            {"name": "Sign in", "request": {"method": "POST", "url": "/login", "body": {"mode": "urlencoded", "urlencoded": [{"key": "user", "value": "jane"}]}}},
+            {"name": "Note", "request": {"method": "PUT", "url": "/notes/1", "body": {"mode": "raw", "raw": "plain text"}}}
+          ]
+        }
+      ]
+    }
+  ]
+}`
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	source, out := filepath.Join(dir, "shop.postman.json"), filepath.Join(dir, "api.json")
+	if err := os.WriteFile(source, []byte(collection), 0644); err != nil { t.Fatal(err) }
+
+	command := newImportCommand()
+	command.SetArgs([]string{source, "--out", out})
+	if err := command.Execute(); err != nil { t.Fatalf("import returned %v", err) }
+
+	data, err := os.ReadFile(out)
+	if err != nil { t.Fatal(err) }
+	imported := cmd.Structure{}
+	if err := json.Unmarshal(data, &imported); err != nil { t.Fatalf("import wrote an unreadable file: %v", err) }
+
+	if imported.BaseURL.Development != "https://shop.example.com" { t.Errorf("import took the base url %q, want https://shop.example.com", imported.BaseURL.Development) }
+
+	// the top level request is the current pipeline, GET being the default method
+	wantCurrent := []cmd.PipelineBody{{Name: "List users", Endpoint: "/users?page=1", Headers: map[string]any{"X-Team": "core"}}}
+	if !reflect.DeepEqual(imported.PipelineBody, wantCurrent) { t.Errorf("import made the current pipeline %+v, want %+v", imported.PipelineBody, wantCurrent) }
+
+	// the folder is a pipeline holding the requests of its sub folders as well
+	wantOrders := []cmd.PipelineBody{
+		{Name: "Create order", Method: "POST", Endpoint: "/orders", Body: map[string]any{"items": []any{float64(1), float64(2)}}},
+		{Name: "Upload", Method: "POST", Endpoint: "/files", BodyType: "multipart", Body: map[string]any{"file": map[string]any{"file": "/tmp/invoice.pdf"}, "note": "paid"}},
+		{Name: "Sign in", Method: "POST", Endpoint: "/login", BodyType: "form", Body: map[string]any{"user": "jane"}},
+		{Name: "Note", Method: "PUT", Endpoint: "/notes/1", BodyType: "raw", Body: "plain text"},
+	}
+	if len(imported.CustomPipelines) != 1 { t.Fatalf("import made pipelines %v, want Orders only", imported.PipelineNames()) }
+	if got := imported.CustomPipelines["Orders"].Steps; !reflect.DeepEqual(got, wantOrders) { t.Errorf("import made the Orders pipeline\n%+v\nwant\n%+v", got, wantOrders) }
+
+	// an existing file is only replaced when forced
+	command = newImportCommand()
+	command.SetArgs([]string{source, "--out", out})
+	command.SilenceErrors, command.SilenceUsage = true, true
+	if err := command.Execute(); err == nil { t.Errorf("import overwrote %s without --force", out) }
+}
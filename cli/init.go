@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/config"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+	"github.com/spf13/cobra"
+)
+
+// initOptions are the answers used to scaffold a configuration file
+type initOptions struct {
+	development string
+	staging string
+	production string
+	auth string
+	loginRoute string
+	tokenLocation string
+	yes bool
+	force bool
+}
+
+// initQuestion asks for one answer unless it was given as a flag
+type initQuestion struct {
+	flag string
+	prompt string
+	answer *string
+}
+
+func newInitCommand() *cobra.Command {
+	options := &initOptions{}
+
+	command := &cobra.Command{
+		Use: "init [file]",
+		Short: "Create a configuration file through a short wizard",
+		Long: "Init asks for the base urls, the auth type, the login route and the token location, then writes\n" +
+			"api.json (or the given json/yaml file) with a sample current pipeline and one custom pipeline.\n" +
+			"Answers can be given as flags, --yes skips the questions left and keeps their defaults",
+		Example: "  apee-i init\n  apee-i init api.yaml --dev-url http://localhost:3000 --auth none --yes",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			file := "api.json"
+			if len(args) == 1 { file = args[0] }
+
+			if _, err := config.ParserFor(file); err != nil { return err }
+			if _, err := os.Stat(file); err == nil && !options.force {
+				return fmt.Errorf("%s already exists, use --force to overwrite it", file)
+			}
+
+			if !options.yes && isTerminal(os.Stdin) {
+				if err := options.ask(c, c.InOrStdin(), c.OutOrStdout()); err != nil { return err }
+			}
+			if err := options.check(); err != nil { return err }
+
+			if err := writeConfig(file, options.config(), true); err != nil { return err }
+
+			// reading the file back makes sure the scaffold is valid
+			if _, _, err := loadConfig(file); err != nil { return err }
+
+			fmt.Println(utils.Green + "Created " + file + utils.Reset)
+			return nil
+		},
+	}
+	command.Flags().StringVar(&options.development, "dev-url", "http://localhost:8000/api", "base url of the development environment")
+	command.Flags().StringVar(&options.staging, "staging-url", "", "base url of the staging environment")
+	command.Flags().StringVar(&options.production, "production-url", "", "base url of the production environment")
+	command.Flags().StringVar(&options.auth, "auth", "JWT", "auth type (JWT/none)")
+	command.Flags().StringVar(&options.loginRoute, "login-route", "/login", "route hit with the credentials to log in")
+	command.Flags().StringVar(&options.tokenLocation, "token-location", "data.access_token", "dot separated path of the token in the login response")
+	command.Flags().BoolVarP(&options.yes, "yes", "y", false, "do not ask anything, use the flags and defaults")
+	command.Flags().BoolVar(&options.force, "force", false, "overwrite the file if it already exists")
+	command.RegisterFlagCompletionFunc("auth", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"JWT", "none"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return command
+}
+
+// ask prompts for every answer that was not given as a flag, an empty
+// answer keeps the default shown in brackets
+func (o *initOptions) ask(c *cobra.Command, in io.Reader, out io.Writer) error {
+	questions := []initQuestion{
+		{"dev-url", "Development base url", &o.development},
+		{"staging-url", "Staging base url (optional)", &o.staging},
+		{"production-url", "Production base url (optional)", &o.production},
+		{"auth", "Auth type (JWT/none)", &o.auth},
+	}
+	scanner := bufio.NewScanner(in)
+
+	for i := 0; i < len(questions); i++ {
+		question := questions[i]
+		if !c.Flags().Changed(question.flag) {
+			fmt.Fprintf(out, "%s [%s]: ", question.prompt, *question.answer)
+			if !scanner.Scan() { return scanner.Err() }
+			if answer := strings.TrimSpace(scanner.Text()); answer != "" { *question.answer = answer }
+		}
+
+		// the login questions only make sense with an auth
+		if question.flag == "auth" && !strings.EqualFold(o.auth, "none") {
+			questions = append(questions,
+				initQuestion{"login-route", "Login route", &o.loginRoute},
+				initQuestion{"token-location", "Token location in the login response", &o.tokenLocation},
+			)
+		}
+	}
+
+	return nil
+}
+
+// check makes sure the answers can form a valid configuration
+func (o *initOptions) check() error {
+	if o.development == "" { return fmt.Errorf("the development base url is required") }
+
+	switch strings.ToLower(o.auth) {
+	case "jwt": o.auth = "JWT"
+	case "none": o.auth = "none"
+	default: return fmt.Errorf("unknown auth type %q, use JWT or none", o.auth)
+	}
+
+	return nil
+}
+
+// config builds the configuration out of the answers
+func (o *initOptions) config() *cmd.Structure {
+	fileContents := &cmd.Structure{
+		BaseURL: cmd.Environments{Development: o.development, Staging: o.staging, Production: o.production},
+		LoginDetails: cmd.LoginDetails{Type: o.auth},
+		PipelineBody: []cmd.PipelineBody{
+			{Name: "health", Endpoint: "/test", Tags: []string{"smoke"}},
+		},
+		CustomPipelines: map[string]cmd.Pipeline{
+			"users": {Steps: []cmd.PipelineBody{
+				{Name: "listUsers", Endpoint: "/users", Tags: []string{"users"}},
+				{Name: "createUser", Endpoint: "/users", Method: "POST", Body: map[string]any{"name": "John Doe", "email": "johndoe@gmail.com"}, ExpectedStatusCode: 201, Capture: map[string]string{"userId": "data.id"}, Tags: []string{"users"}},
+				{Name: "getUser", Endpoint: "/users/{{userId}}", Tags: []string{"users"}},
+			}},
+		},
+	}
+
+	if o.auth != "none" {
+		fileContents.LoginDetails.Route = o.loginRoute
+		fileContents.LoginDetails.TokenLocation = o.tokenLocation
+		credentials := map[string]any{"email": "example@gmail.com", "password": "Example@123"}
+		fileContents.Credentials.Development = credentials
+		if o.staging != "" { fileContents.Credentials.Staging = credentials }
+		if o.production != "" { fileContents.Credentials.Production = credentials }
+	}
+
+	return fileContents
+}
+
+// isTerminal tells if a file is an interactive terminal rather than a pipe
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode() & os.ModeCharDevice != 0
+}
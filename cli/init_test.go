@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestInitAsk(t *testing.T) {
+	defaults := initOptions{development: "http://localhost:8000/api", auth: "JWT", loginRoute: "/login", tokenLocation: "data.access_token"}
+
+	// empty answers keep the defaults and the login questions follow the auth
+	options := defaults
+	out := &bytes.Buffer{}
+	if err := options.ask(newInitCommand(), strings.NewReader("http://dev\n\nhttps://api.example.com\n\n/auth/login\n\n"), out); err != nil { t.Fatal(err) }
+
+	want := initOptions{development: "http://dev", production: "https://api.example.com", auth: "JWT", loginRoute: "/auth/login", tokenLocation: "data.access_token"}
+	if options != want { t.Errorf("ask() answered %+v, want %+v", options, want) }
+	prompts := "Development base url [http://localhost:8000/api]: Staging base url (optional) []: Production base url (optional) []: " +
+		"Auth type (JWT/none) [JWT]: Login route [/login]: Token location in the login response [data.access_token]: "
+	if out.String() != prompts { t.Errorf("ask() prompted\n%q\nwant\n%q", out.String(), prompts) }
+
+	// flags are not asked again and no auth skips the login questions
+	command := newInitCommand()
+	command.Flags().Set("dev-url", "http://flag")
+	options = defaults
+	options.development = "http://flag"
+	out.Reset()
+	if err := options.ask(command, strings.NewReader("\n\nnone\n/ignored\n"), out); err != nil { t.Fatal(err) }
+
+	if options.development != "http://flag" || options.auth != "none" || options.loginRoute != "/login" { t.Errorf("ask() with flags answered %+v", options) }
+	if strings.Contains(out.String(), "Development") || strings.Contains(out.String(), "Login route") { t.Errorf("ask() with flags prompted %q", out.String()) }
+
+	// running out of input keeps what is left as it is
+	options = defaults
+	if err := options.ask(newInitCommand(), strings.NewReader("http://short\n"), &bytes.Buffer{}); err != nil { t.Errorf("ask() on a short input returned %v", err) }
+	if options.development != "http://short" || options.auth != "JWT" { t.Errorf("ask() on a short input answered %+v", options) }
+}
+
+func TestInitCheck(t *testing.T) {
+	// the auth type is normalised, anything else is refused
+	for auth, want := range map[string]string{"jwt": "JWT", "JWT": "JWT", "None": "none", "basic": "", "": ""} {
+		options := initOptions{development: "http://dev", auth: auth}
+		err := options.check()
+		if want == "" && err == nil { t.Errorf("check() accepted the auth type %q", auth) }
+		if want != "" && (err != nil || options.auth != want) { t.Errorf("check() with auth %q = %q, %v, want %q", auth, options.auth, err, want) }
+	}
+
+	options := initOptions{auth: "JWT"}
+	if err := options.check(); err == nil { t.Errorf("check() accepted an empty development base url") }
+}
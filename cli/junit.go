@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// junitSuites is the root of a JUnit XML report
+type junitSuites struct {
+	XMLName xml.Name `xml:"testsuites"`
+	Name string `xml:"name,attr"`
+	Tests int `xml:"tests,attr"`
+	Failures int `xml:"failures,attr"`
+	Skipped int `xml:"skipped,attr"`
+	Time string `xml:"time,attr"`
+	Suites []*junitSuite `xml:"testsuite"`
+}
+
+// junitSuite holds the steps of a pipeline, or of a single row of a
+// pipeline driven by a data file
+type junitSuite struct {
+	Name string `xml:"name,attr"`
+	Tests int `xml:"tests,attr"`
+	Failures int `xml:"failures,attr"`
+	Skipped int `xml:"skipped,attr"`
+	Time string `xml:"time,attr"`
+	Cases []junitCase `xml:"testcase"`
+	duration time.Duration
+}
+
+type junitCase struct {
+	Name string `xml:"name,attr"`
+	ClassName string `xml:"classname,attr"`
+	Time string `xml:"time,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Failure *junitFailure `xml:"failure,omitempty"`
+	Skipped *struct{} `xml:"skipped,omitempty"`
+}
+
+// junitProperties hold the timings of the request of a step, in seconds
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text string `xml:",chardata"`
+}
+
+// writeJUnit stores the results of a run as a JUnit XML report, one test
+// suite per pipeline and per row of the pipelines driven by a data file
+func writeJUnit(file string, results []cmd.StepResult) error {
+	report := &junitSuites{Name: "apee-i"}
+	suites := map[string]*junitSuite{}
+	var total time.Duration
+
+	for _, result := range results {
+		name := result.Pipeline
+		if result.Iteration != "" { name += " [" + result.Iteration + "]" }
+
+		suite, exists := suites[name]
+		if !exists {
+			suite = &junitSuite{Name: name}
+			suites[name] = suite
+			report.Suites = append(report.Suites, suite)
+		}
+
+		testCase := junitCase{Name: result.Step, ClassName: name, Time: seconds(result.Duration), Properties: timingProperties(result.Timings)}
+		switch {
+		case result.Err != nil:
+			testCase.Failure = &junitFailure{Message: result.Err.Error(), Text: result.Err.Error()}
+			suite.Failures++; report.Failures++
+		case result.Skipped:
+			testCase.Skipped = &struct{}{}
+			suite.Skipped++; report.Skipped++
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++; report.Tests++
+		suite.duration += result.Duration
+		total += result.Duration
+	}
+
+	for _, suite := range report.Suites { suite.Time = seconds(suite.duration) }
+	report.Time = seconds(total)
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil { return fmt.Errorf("Could not write JUnit report: %s", err.Error()) }
+
+	return os.WriteFile(file, append([]byte(xml.Header), append(data, '\n')...), 0644)
+}
+
+// timingProperties lists the phases of a request, steps without one have no properties
+func timingProperties(timings cmd.Timings) *junitProperties {
+	if !timings.Measured() { return nil }
+
+	properties := &junitProperties{}
+	for _, phase := range []struct{ name string; duration time.Duration }{
+		{"dns", timings.DNS}, {"connect", timings.Connect}, {"tls", timings.TLS},
+		{"ttfb", timings.TTFB}, {"transfer", timings.Transfer}, {"total", timings.Total},
+	} {
+		properties.Properties = append(properties.Properties, junitProperty{Name: phase.name, Value: fmt.Sprintf("%.6f", phase.duration.Seconds())})
+	}
+	return properties
+}
+
+// seconds formats a duration the way JUnit reports expect it
+func seconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	timings := cmd.Timings{Connect: time.Millisecond, TTFB: 20 * time.Millisecond, Total: 25 * time.Millisecond}
+	results := []cmd.StepResult{
+		{Pipeline: "current", Step: "login", Duration: 25 * time.Millisecond, Timings: timings},
+		{Pipeline: "current", Step: "profile", Err: errors.New("Assertion failed: status == 200"), Duration: time.Second},
+		{Pipeline: "users", Iteration: "row 1", Step: "create", Duration: 500 * time.Millisecond},
+		{Pipeline: "users", Iteration: "row 1", Step: "delete", Skipped: true},
+		{Pipeline: "users", Iteration: "row 2", Step: "create", Err: errors.New("Could not hit API: EOF")},
+	}
+
+	file := filepath.Join(t.TempDir(), "report.xml")
+	if err := writeJUnit(file, results); err != nil { t.Fatal(err) }
+	data, err := os.ReadFile(file)
+	if err != nil { t.Fatal(err) }
+
+	report := junitSuites{}
+	if err := xml.Unmarshal(data, &report); err != nil { t.Fatalf("writeJUnit() wrote an unreadable report: %v\n%s", err, data) }
+
+	if report.Tests != 5 || report.Failures != 2 || report.Skipped != 1 || report.Time != "1.525" {
+		t.Errorf("report counts %d tests, %d failures, %d skipped in %s, want 5, 2, 1 in 1.525", report.Tests, report.Failures, report.Skipped, report.Time)
+	}
+
+	// a suite per pipeline and per row, in the order they ran
+	suites := []struct {
+		name string
+		tests, failures, skipped int
+	}{
+		{"current", 2, 1, 0},
+		{"users [row 1]", 2, 0, 1},
+		{"users [row 2]", 1, 1, 0},
+	}
+	if len(report.Suites) != len(suites) { t.Fatalf("report has %d suites, want %d", len(report.Suites), len(suites)) }
+	for i, want := range suites {
+		got := report.Suites[i]
+		if got.Name != want.name || got.Tests != want.tests || got.Failures != want.failures || got.Skipped != want.skipped {
+			t.Errorf("suite %d is %s with %d tests, %d failures, %d skipped, want %+v", i, got.Name, got.Tests, got.Failures, got.Skipped, want)
+		}
+	}
+
+	// the failure carries the error and only measured steps have timings
+	profile := report.Suites[0].Cases[1]
+	if profile.Failure == nil || profile.Failure.Message != "Assertion failed: status == 200" { t.Errorf("profile failed with %+v", profile.Failure) }
+	if login := report.Suites[0].Cases[0]; login.Properties == nil || len(login.Properties.Properties) != 6 || login.Properties.Properties[3] != (junitProperty{Name: "ttfb", Value: "0.020000"}) {
+		t.Errorf("login has the properties %+v", login.Properties)
+	}
+	if profile.Properties != nil { t.Errorf("profile has timings without a request: %+v", profile.Properties) }
+	if report.Suites[1].Cases[1].Skipped == nil { t.Errorf("delete is not reported as skipped") }
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/runner"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+	"github.com/Jeffail/gabs/v2"
+	"github.com/spf13/cobra"
+)
+
+// mockRoute answers a step of the configuration with its expectations
+type mockRoute struct {
+	method string
+	path *regexp.Regexp
+	statusCode int
+	body any
+}
+
+func newMockCommand(global *globalOptions) *cobra.Command {
+	address := ":8080"
+
+	command := &cobra.Command{
+		Use: "mock",
+		Short: "Serve the steps of the configuration file from a local mock server",
+		Long: "Mock starts an HTTP server answering every step with its expectedStatusCode and expectedBody.\n" +
+			"The login route answers with a token placed at token_location, /me always succeeds",
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			_, fileContents, err := loadConfig(global.file)
+			if err != nil { return err }
+
+			routes := mockRoutes(fileContents)
+			fmt.Println(utils.Green + fmt.Sprintf("Mocking %d routes on %s", len(routes), address) + utils.Reset)
+
+			return http.ListenAndServe(address, mockHandler(routes))
+		},
+	}
+	command.Flags().StringVarP(&address, "address", "a", address, "address for the mock server to listen on")
+
+	return command
+}
+
+// mockHandler answers a request with the first route matching its method
+// and path, and with a 404 when none does
+func mockHandler(routes []mockRoute) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		for _, route := range routes {
+			if route.method != r.Method || !route.path.MatchString(r.URL.Path) { continue }
+
+			w.WriteHeader(route.statusCode)
+			json.NewEncoder(w).Encode(route.body)
+			fmt.Println(r.Method, r.URL.Path, route.statusCode)
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"no step matches this route"}`))
+		fmt.Println(utils.Red + r.Method + " " + r.URL.Path + " 404" + utils.Reset)
+	})
+}
+
+// mockRoutes builds a route for the login, /me and every step of every pipeline
+func mockRoutes(fileContents *cmd.Structure) []mockRoute {
+	loginRoute := fileContents.LoginDetails.Route
+	if loginRoute == "" { loginRoute = runner.DefaultLoginRoute }
+
+	token := gabs.New()
+	if fileContents.LoginDetails.TokenLocation != "" { token.SetP("mock-token", fileContents.LoginDetails.TokenLocation) }
+
+	routes := []mockRoute{
+		{method: "POST", path: mockPath(loginRoute), statusCode: 200, body: token.Data()},
+		{method: "GET", path: mockPath("/me"), statusCode: 200, body: map[string]any{}},
+	}
+
+	for _, pipeline := range fileContents.PipelineNames() {
+		for _, step := range fileContents.Pipeline(pipeline) {
+			// gRPC and streaming steps are not served by the mock
+			if step.Kind != "" && step.Kind != "http" { continue }
+
+			route := mockRoute{method: step.Method, path: mockPath(step.Endpoint), statusCode: step.ExpectedStatusCode, body: step.ExpectedBody}
+			if route.method == "" && step.GraphQL != nil { route.method = "POST" }
+			if route.method == "" { route.method = "GET" }
+			// GraphQL answers its operations with a 200, queries or mutations alike
+			if route.statusCode == 0 && route.method == "POST" && step.GraphQL == nil { route.statusCode = 201 }
+			if route.statusCode == 0 { route.statusCode = 200 }
+			if route.body == nil { route.body = map[string]any{} }
+
+			routes = append(routes, route)
+		}
+	}
+
+	return routes
+}
+
+// mockPath turns an endpoint into a pattern where every `{{variable}}` matches a path segment
+func mockPath(endpoint string) *regexp.Regexp {
+	endpoint = strings.SplitN(endpoint, "?", 2)[0]
+
+	parts := regexp.MustCompile(`{{[^}]*}}`).Split(endpoint, -1)
+	for i := range parts { parts[i] = regexp.QuoteMeta(parts[i]) }
+
+	return regexp.MustCompile("^" + strings.Join(parts, "[^/]+") + "$")
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+func TestMockRoutes(t *testing.T) {
+	fileContents := &cmd.Structure{
+		LoginDetails: cmd.LoginDetails{Route: "/auth/login", TokenLocation: "data.token"},
+		PipelineBody: []cmd.PipelineBody{
+			{Endpoint: "/users/{{userId}}?expand=true", ExpectedBody: map[string]any{"id": float64(7)}},
+			{Endpoint: "/users", Method: "POST"},
+			{Endpoint: "/users/{{userId}}", Method: "DELETE", ExpectedStatusCode: 204},
+			{Endpoint: "/graphql", GraphQL: &cmd.GraphQL{Query: "{ me { id } }"}, ExpectedBody: map[string]any{"data": map[string]any{}}},
+		},
+		CustomPipelines: map[string]cmd.Pipeline{
+			"events": {Steps: []cmd.PipelineBody{
+				{Kind: "sse", Endpoint: "/events"},
+				{Endpoint: "/teams/{{team}}/members/{{member}}", Method: "PUT", ExpectedStatusCode: 202},
+			}},
+		},
+	}
+	server := httptest.NewServer(mockHandler(mockRoutes(fileContents)))
+	defer server.Close()
+
+	// each request is answered by the step it matches, with its expectations
+	requests := []struct {
+		method string
+		path string
+		status int
+		body string
+	}{
+		{"POST", "/auth/login", 200, `{"data":{"token":"mock-token"}}`},
+		{"GET", "/me", 200, `{}`},
+		{"GET", "/users/42", 200, `{"id":7}`},
+		{"POST", "/users", 201, `{}`},
+		{"DELETE", "/users/42", 204, ``},
+		{"POST", "/graphql", 200, `{"data":{}}`},
+		{"PUT", "/teams/core/members/jane", 202, `{}`},
+		{"GET", "/users/42/posts", 404, `{"message":"no step matches this route"}`},
+		{"GET", "/users", 404, `{"message":"no step matches this route"}`},
+		{"GET", "/events", 404, `{"message":"no step matches this route"}`},
+	}
+
+	for _, request := range requests {
+		req, err := http.NewRequest(request.method, server.URL + request.path, nil)
+		if err != nil { t.Fatal(err) }
+		res, err := http.DefaultClient.Do(req)
+		if err != nil { t.Fatal(err) }
+
+		var body any
+		decodeErr := json.NewDecoder(res.Body).Decode(&body)
+		res.Body.Close()
+
+		if res.StatusCode != request.status { t.Errorf("%s %s answered %d, want %d", request.method, request.path, res.StatusCode, request.status) }
+		if request.body == "" { continue }
+		got, _ := json.Marshal(body)
+		if decodeErr != nil || string(got) != request.body { t.Errorf("%s %s answered %s, want %s", request.method, request.path, strings.TrimSpace(string(got)), request.body) }
+	}
+}
+
+func TestMockPath(t *testing.T) {
+	path := mockPath("/files/{{name}}.json?download={{flag}}")
+
+	for value, want := range map[string]bool{"/files/report.json": true, "/files/a/b.json": false, "/files/reportxjson": false, "/files/.json": false} {
+		if got := path.MatchString(value); got != want { t.Errorf("mockPath matched %s: %v, want %v", value, got, want) }
+	}
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/spf13/cobra"
+)
+
+// globalOptions are the flags shared by every subcommand
+type globalOptions struct {
+	file string
+	env string
+}
+
+// NewRootCommand builds the whole `apee-i` command tree. Running the root
+// without a subcommand behaves like `apee-i run`
+func NewRootCommand() *cobra.Command {
+	global := &globalOptions{}
+	run := &runOptions{}
+
+	root := &cobra.Command{
+		Use: "apee-i",
+		Short: "Command line based API tester",
+		Long: "apee-i runs the API pipelines described in a json, yaml or toml configuration file",
+		Version: cmd.CurrentVersion,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			return run.execute(global, args)
+		},
+	}
+
+	root.PersistentFlags().StringVarP(&global.file, "file", "f", "", "configuration file, directory or glob (default: apee-i.{json,yaml,yml,toml} or api.json found from the working directory up)")
+	root.PersistentFlags().StringVarP(&global.env, "env", "e", "development", "environment to test against (development/staging/production)")
+	root.RegisterFlagCompletionFunc("file", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "jsonc", "json5", "yaml", "yml", "toml"}, cobra.ShellCompDirectiveFilterFileExt
+	})
+	root.RegisterFlagCompletionFunc("env", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return cmd.EnvironmentNames, cobra.ShellCompDirectiveNoFileComp
+	})
+	run.addFlags(root, global)
+
+	root.AddCommand(
+		newRunCommand(global),
+		newValidateCommand(global),
+		newSchemaCommand(),
+		newInitCommand(),
+		newImportCommand(),
+		newExportCommand(global),
+		newMockCommand(global),
+		newTokenCommand(global),
+		newHistoryCommand(global),
+		newEnvCommand(global),
+		newVersionCommand(),
+		newUpdateCommand(),
+	)
+
+	return root
+}
+
+// Execute runs the command tree against the process arguments
+func Execute() {
+	root := NewRootCommand()
+	root.SetArgs(legacyArgs(os.Args[1:]))
+
+	if err := root.Execute(); err != nil { os.Exit(1) }
+}
+
+// legacyFlags are the long flags older releases read with a single dash
+var legacyFlags = map[string]bool{"help": true, "version": true, "file": true, "env": true, "pipeline": true, "name": true}
+
+// legacyArgs rewrites the single dash long flags of older releases such as
+// `-file=api.yaml` into `--file=api.yaml` so existing scripts keep working.
+// Anything else, combined short flags and negative numbers included, is
+// left as it is
+func legacyArgs(args []string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = arg
+		if arg == "--" { copy(out[i:], args[i:]); break }
+
+		name := strings.SplitN(strings.TrimPrefix(arg, "-"), "=", 2)[0]
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && legacyFlags[name] { out[i] = "-" + arg }
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLegacyArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"single dash long flag", []string{"run", "-file=api.yaml", "-env", "staging"}, []string{"run", "--file=api.yaml", "--env", "staging"}},
+		{"double dash flag", []string{"run", "--pipeline", "all"}, []string{"run", "--pipeline", "all"}},
+		{"combined short flags", []string{"run", "-vp", "current"}, []string{"run", "-vp", "current"}},
+		{"negative value", []string{"run", "--seed", "-42"}, []string{"run", "--seed", "-42"}},
+		{"unknown single dash flag", []string{"run", "-tags=smoke"}, []string{"run", "-tags=smoke"}},
+		{"after terminator", []string{"run", "--", "-file"}, []string{"run", "--", "-file"}},
+		{"legacy version", []string{"-version"}, []string{"--version"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := legacyArgs(test.args); !reflect.DeepEqual(got, test.want) { t.Errorf("legacyArgs(%q) = %q, want %q", test.args, got, test.want) }
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/runner"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+	"github.com/spf13/cobra"
+)
+
+// runOptions are the selectors deciding which steps of the file are run
+type runOptions struct {
+	pipeline string
+	name string
+	step string
+	tags []string
+	grep string
+	junit string
+	seed int64
+	verbose bool
+	compareBaseline bool
+	noHistory bool
+}
+
+func newRunCommand(global *globalOptions) *cobra.Command {
+	run := &runOptions{}
+
+	command := &cobra.Command{
+		Use: "run [pipeline]",
+		Short: "Run the current pipeline, a custom pipeline or all of them",
+		Long: "Run logs in with the credentials of the selected environment and calls the selected steps.\n" +
+			"The pipeline can be given as an argument: `current`, `all` or the name of a custom pipeline",
+		Example: "  apee-i run\n  apee-i run users --env staging\n  apee-i run --tags smoke,users\n  apee-i run --step getUser\n  apee-i run --junit report.xml\n  apee-i run --compare-baseline",
+		Args: cobra.MaximumNArgs(1),
+		ValidArgsFunction: pipelineCompletion(global),
+		RunE: func(c *cobra.Command, args []string) error {
+			return run.execute(global, args)
+		},
+	}
+	run.addFlags(command, global)
+
+	return command
+}
+
+// addFlags registers the selector flags on a command
+func (o *runOptions) addFlags(command *cobra.Command, global *globalOptions) {
+	command.Flags().StringVarP(&o.pipeline, "pipeline", "p", "current", "pipeline type to run (current/all/custom)")
+	command.Flags().StringVarP(&o.name, "name", "n", "", "custom pipeline name, used with --pipeline=custom")
+	command.Flags().StringVar(&o.step, "step", "", "run only the step with this name, across all pipelines")
+	command.Flags().StringSliceVar(&o.tags, "tags", nil, "run only the steps having any of these tags (smoke,users)")
+	command.Flags().StringVar(&o.grep, "grep", "", "run only the steps whose endpoint matches this regex")
+	command.Flags().StringVar(&o.junit, "junit", "", "write the results as a JUnit XML report to this file")
+	command.Flags().BoolVarP(&o.verbose, "verbose", "v", false, "print the DNS, connect, TLS, time to first byte and transfer timings of every request")
+	command.Flags().BoolVar(&o.compareBaseline, "compare-baseline", false, "fail the steps slower than the median of their earlier runs, by the limits of baseline")
+	command.Flags().BoolVar(&o.noHistory, "no-history", false, "leave the timings of this run out of " + cmd.HistoryFile + ", next to the configuration")
+	command.Flags().Int64Var(&o.seed, "seed", 0, "seed of the random generators such as {{uuid}}, to reproduce the values of a run (0 picks a new one)")
+
+	command.RegisterFlagCompletionFunc("pipeline", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"current", "all", "custom"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	command.RegisterFlagCompletionFunc("name", pipelineCompletion(global))
+	command.RegisterFlagCompletionFunc("step", stepCompletion(global))
+}
+
+// filter builds the step filter out of the selector flags
+func (o *runOptions) filter() (cmd.StepFilter, error) {
+	filter := cmd.StepFilter{Step: o.step, Tags: o.tags}
+	if o.grep == "" { return filter, nil }
+
+	expression, err := regexp.Compile(o.grep)
+	if err != nil { return filter, fmt.Errorf("Invalid --grep expression: %s", err.Error()) }
+	filter.Grep = expression
+
+	return filter, nil
+}
+
+// execute logs in and calls the selected pipeline or steps, then sums up
+// how every step went. The run fails when any step did
+func (o *runOptions) execute(global *globalOptions, args []string) error {
+	filter, err := o.filter()
+	if err != nil { return err }
+
+	// a positional pipeline takes over the --pipeline and --name flags
+	pipeline, name := o.pipeline, o.name
+	if len(args) == 1 {
+		switch args[0] {
+		case cmd.CurrentPipelineName, "all": pipeline = args[0]
+		default: pipeline, name = "custom", args[0]
+		}
+	}
+
+	fileContext, fileContents, err := loadEnvironment(global)
+	if err != nil { return err }
+
+	if pipeline == "custom" {
+		if _, exists := fileContents.CustomPipelines[name]; !exists { return fmt.Errorf("No such pipeline exists") }
+	} else if pipeline != cmd.CurrentPipelineName && pipeline != "all" {
+		return fmt.Errorf("No such pipeline exists, use one of current, all or custom")
+	}
+
+	started := time.Now()
+	if o.seed != 0 { cmd.SeedGenerators(o.seed) }
+	if err := runner.OpenCookieJar(fileContents); err != nil { return err }
+	fileContents.Verbose = o.verbose
+	fileContext.Login(fileContents)
+
+	// selecting the steps to call
+	switch {
+	case !filter.Empty(): fileContext.CallFilteredSteps(fileContents, filter)
+	case pipeline == "custom": fileContext.CallSingleCustomPipeline(fileContents, name)
+	case pipeline == "all": fileContext.CallCustomPipelines(fileContents)
+	default: fileContext.CallCurrentPipeline(fileContents)
+	}
+
+	if err := runner.SaveCookieJar(fileContents); err != nil { return err }
+	if len(fileContents.Results) > 0 { utils.SummaryLogger(fileContents.Results) }
+	if o.junit != "" {
+		if err := writeJUnit(o.junit, fileContents.Results); err != nil { return err }
+	}
+
+	regressions, err := o.history(fileContents, started)
+	if err != nil { return err }
+
+	if failures := fileContents.Failures(); failures > 0 {
+		return fmt.Errorf("%d of %d steps failed", failures, len(fileContents.Results))
+	}
+	if regressions > 0 { return fmt.Errorf("%d of %d steps regressed against their baseline", regressions, len(fileContents.Results)) }
+	return nil
+}
+
+// history compares the run with the earlier ones when asked to, then adds
+// its timings to the history file. It tells how many steps regressed
+func (o *runOptions) history(fileContents *cmd.Structure, started time.Time) (int, error) {
+	entries := cmd.HistoryEntries(fileContents.Results, fileContents.ActiveEnvironment, started)
+	regressions := []cmd.Regression{}
+
+	if o.compareBaseline {
+		history, err := cmd.ReadHistory(historyPath(fileContents))
+		if err != nil { return 0, err }
+		regressions, err = cmd.Regressions(history, entries, fileContents.Baseline)
+		if err != nil { return 0, err }
+		utils.RegressionLogger(regressions)
+	}
+
+	if !o.noHistory && len(entries) > 0 {
+		if err := cmd.AppendHistory(historyPath(fileContents), entries); err != nil { return 0, err }
+	}
+	return len(regressions), nil
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd/config"
+	"github.com/spf13/cobra"
+)
+
+func newSchemaCommand() *cobra.Command {
+	out := ""
+
+	command := &cobra.Command{
+		Use: "schema",
+		Short: "Print the JSON Schema of the configuration file",
+		Long: "Schema prints the JSON Schema used to validate api.json and api.yaml. Point your editor at it\n" +
+			"for autocompletion and inline validation, the same schema is used by `apee-i validate`",
+		Example: "  apee-i schema --out apee-i.schema.json",
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			data, err := json.MarshalIndent(config.GenerateSchema(), "", "  ")
+			if err != nil { return err }
+			data = append(data, '\n')
+
+			if out == "" { _, err = os.Stdout.Write(data); return err }
+			if err := os.WriteFile(out, data, 0644); err != nil { return err }
+
+			fmt.Println("Schema written to " + out)
+			return nil
+		},
+	}
+	command.Flags().StringVarP(&out, "out", "o", "", "file to write the schema to instead of stdout")
+
+	return command
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd/runner"
+	"github.com/spf13/cobra"
+)
+
+func newTokenCommand(global *globalOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use: "token",
+		Short: "Inspect or reset the cached login token",
+	}
+
+	command.AddCommand(
+		&cobra.Command{
+			Use: "show",
+			Short: "Print the cached token",
+			Args: cobra.NoArgs,
+			RunE: func(c *cobra.Command, args []string) error {
+				data, err := os.ReadFile(runner.TokenFile)
+				if err != nil || len(data) == 0 { return fmt.Errorf("No token is cached yet") }
+
+				fmt.Println(string(data))
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use: "clear",
+			Short: "Remove the cached token",
+			Args: cobra.NoArgs,
+			RunE: func(c *cobra.Command, args []string) error {
+				if err := os.Remove(runner.TokenFile); err != nil && !os.IsNotExist(err) { return err }
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use: "refresh",
+			Short: "Log in again with the credentials of the selected environment",
+			Args: cobra.NoArgs,
+			RunE: func(c *cobra.Command, args []string) error {
+				fileContext, fileContents, err := loadEnvironment(global)
+				if err != nil { return err }
+
+				if err := os.Remove(runner.TokenFile); err != nil && !os.IsNotExist(err) { return err }
+				fileContext.Login(fileContents)
+				return nil
+			},
+		},
+	)
+
+	return command
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd/config"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+	"github.com/spf13/cobra"
+)
+
+func newValidateCommand(global *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use: "validate [file]",
+		Short: "Check a configuration file for mistakes",
+		Long: "Validate reports syntax errors, unknown keys, wrong types, missing endpoints and undefined variables\n" +
+			"with their file:line:column. With --env it also checks that the environment is defined",
+		Args: cobra.MaximumNArgs(1),
+		ValidArgsFunction: func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"json", "jsonc", "json5", "yaml", "yml", "toml"}, cobra.ShellCompDirectiveFilterFileExt
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			file := global.file
+			if len(args) == 1 { file = args[0] }
+			if file == "" {
+				discovered, err := config.Discover(".")
+				if err != nil { return err }
+				file = config.DisplayName(discovered)
+			}
+
+			_, fileContents, err := loadConfig(file)
+			if err != nil { return err }
+
+			if c.Flags().Changed("env") {
+				if err := fileContents.SelectEnvironment(global.env); err != nil { return err }
+			}
+
+			fmt.Println(utils.Green + file + " is valid" + utils.Reset)
+			return nil
+		},
+	}
+}
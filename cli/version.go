@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/spf13/cobra"
+)
+
+func newVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use: "version",
+		Short: "Print the version of apee-i",
+		Args: cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			cmd.Version()
+		},
+	}
+}
+
+func newUpdateCommand() *cobra.Command {
+	options := cmd.UpdateOptions{}
+
+	command := &cobra.Command{
+		Use: "update",
+		Short: "Update apee-i to the latest version",
+		Long: "Update looks for a newer release in the release feed, downloads the binary for this platform,\n" +
+			"verifies its SHA-256 checksum and signature and replaces the running executable.\n" +
+			"Builds without an update public key refuse to install anything unless given --insecure",
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			return cmd.Update(options)
+		},
+	}
+	command.Flags().BoolVar(&options.CheckOnly, "check", false, "only report whether a newer version is available")
+	command.Flags().BoolVar(&options.Insecure, "insecure", false, "install without a signature check, trusting the checksums of the feed")
+	command.Flags().StringVar(&options.FeedURL, "feed", "", "release feed url (default $APEE_I_RELEASE_FEED or " + cmd.DefaultReleaseFeed + ")")
+
+	return command
+}
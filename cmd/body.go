@@ -0,0 +1,27 @@
+package cmd
+
+import "path/filepath"
+
+// FileReference tells if a body value is a `{file: path}` reference and returns the path
+func FileReference(value any) (string, bool) {
+	object, ok := value.(map[string]any)
+	if !ok { return "", false }
+
+	path, ok := object["file"].(string)
+	return path, ok
+}
+
+// BaseDir is the directory the relative paths of a file of the configuration
+// start at, dir being the one of that file, or the directory of the
+// configuration when it is not known
+func (s *Structure) BaseDir(dir string) string {
+	if dir != "" { return dir }
+	return s.ConfigDir
+}
+
+// Resolve makes a path written in a file of the configuration absolute,
+// dir being the directory of that file
+func (s *Structure) Resolve(dir string, path string) string {
+	if filepath.IsAbs(path) { return path }
+	return filepath.Join(s.BaseDir(dir), path)
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/Jeffail/gabs/v2"
 )
@@ -11,31 +13,40 @@ type APIStructure struct {
 	Method             string
 	Endpoint           string
 	Body               any
+	BodyType           string
 	ExpectedStatusCode int
 	ExpectedBody       any
 	Headers            any
+	Query              map[string]any
+	Cookies            map[string]any
+	Timeout            string
+	FollowRedirects    *bool
+	Dir                string
 }
 
 // APIResponse defines all the elements that a request response will contain
 type APIResponse struct {
 	StatusCode int
 	Body       *gabs.Container
+	Headers    http.Header
+	Redirects  []string
+	Timings    Timings
 }
 
 // Credentials contains all the login properties
 // with respect to the 3 different environments
 type Credentials struct {
-	Development any `yaml:"development" json:"development"`
-	Staging any `yaml:"staging" json:"staging"`
-	Production any `yaml:"production" json:"production"`
+	Development any `yaml:"development,omitempty" json:"development,omitempty" description:"Login body sent in development"`
+	Staging any `yaml:"staging,omitempty" json:"staging,omitempty" description:"Login body sent in staging"`
+	Production any `yaml:"production,omitempty" json:"production,omitempty" description:"Login body sent in production"`
 }
 
 // Environments are all the different envs that user
 // could mention in defining baseUrl and credentials
 type Environments struct {
-	Development string `yaml:"development" json:"development"`
-	Staging string `yaml:"staging" json:"staging"`
-	Production string `yaml:"production" json:"production"`
+	Development string `yaml:"development,omitempty" json:"development,omitempty" description:"Base url of the development environment"`
+	Staging string `yaml:"staging,omitempty" json:"staging,omitempty" description:"Base url of the staging environment"`
+	Production string `yaml:"production,omitempty" json:"production,omitempty" description:"Base url of the production environment"`
 }
 
 // LoginDetails are used to tell the program
@@ -43,32 +54,234 @@ type Environments struct {
 // 2. what type of auth is it
 // 3. where is the token found in response
 type LoginDetails struct {
-	Route string `yaml:"route" json:"route"`
-	TokenLocation string `yaml:"token_location" json:"token_location"`
-	Token string 
+	Route string `yaml:"route" json:"route" description:"Route hit with the credentials to log in"`
+	Type string `yaml:"type,omitempty" json:"type,omitempty" description:"Type of auth, none skips logging in" enum:"JWT,none"`
+	TokenLocation string `yaml:"token_location" json:"token_location" description:"Dot separated path of the token in the login response"`
+	Token string `yaml:"-" json:"-"`
+}
+
+// Defaults are sent with every request, beneath what a step sets itself
+type Defaults struct {
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty" description:"Headers added to every request"`
+	Query map[string]any `yaml:"query,omitempty" json:"query,omitempty" description:"Query parameters added to every request"`
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty" description:"Time every request may take, such as 5s or 500ms" format:"duration"`
+}
+
+// CookieSettings tell how the cookies set by responses are kept and sent
+// back. The jar is shared by the whole run unless each pipeline gets its own
+type CookieSettings struct {
+	Scope string `yaml:"scope,omitempty" json:"scope,omitempty" description:"run shares one jar across the pipelines, pipeline gives each one a copy of the cookies of the login and beforeAll, none keeps no cookies, run by default" enum:"run,pipeline,none"`
+	Persist bool `yaml:"persist,omitempty" json:"persist,omitempty" description:"Keeps the cookies of every environment between runs in cookies.json, next to the configuration"`
+}
+
+// TLS tells how the certificates of an environment are checked and which
+// client certificate is presented to servers asking for one
+type TLS struct {
+	CA string `yaml:"ca,omitempty" json:"ca,omitempty" description:"PEM bundle of the certificate authorities trusted beside the system ones, relative to the file declaring it"`
+	Cert string `yaml:"cert,omitempty" json:"cert,omitempty" description:"PEM client certificate for mutual TLS, relative to the file declaring it"`
+	Key string `yaml:"key,omitempty" json:"key,omitempty" description:"PEM private key of the client certificate, relative to the file declaring it"`
+	ServerName string `yaml:"serverName,omitempty" json:"serverName,omitempty" description:"Name the server certificate is checked against, the host of the url by default"`
+	MinVersion string `yaml:"minVersion,omitempty" json:"minVersion,omitempty" description:"Oldest TLS version accepted, TLS1.2 by default" enum:"TLS1.0,TLS1.1,TLS1.2,TLS1.3"`
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify,omitempty" json:"insecureSkipVerify,omitempty" description:"Accepts any server certificate, only for servers you trust"`
+	Dir string `yaml:"-" json:"-"`
+}
+
+// TransportSettings tell how the connections of an environment are made
+type TransportSettings struct {
+	Proxy string `yaml:"proxy,omitempty" json:"proxy,omitempty" description:"http, https or socks5 proxy url, with its credentials as user:password@ when needed. Every request, forced HTTP/2 and websockets included, goes through it"`
+	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty" description:"HTTP version forced on every request, negotiated by default" enum:"http1.1,http2"`
+	DisableKeepAlives bool `yaml:"disableKeepAlives,omitempty" json:"disableKeepAlives,omitempty" description:"Opens a new connection for every request"`
+	DisableCompression bool `yaml:"disableCompression,omitempty" json:"disableCompression,omitempty" description:"Stops asking for gzip responses"`
+}
+
+// Baseline tells when a step counts as slower than in the earlier runs, for
+// `run --compare-baseline`. A step regressed when it is slower than the
+// median of its earlier runs by more than every limit that is set
+type Baseline struct {
+	Threshold string `yaml:"threshold,omitempty" json:"threshold,omitempty" description:"Slowdown a step may have over its baseline, such as 200ms" format:"duration"`
+	Percentage float64 `yaml:"percentage,omitempty" json:"percentage,omitempty" description:"Slowdown a step may have over its baseline in percent of it, 20 when no threshold is set either"`
+	Runs int `yaml:"runs,omitempty" json:"runs,omitempty" description:"Number of earlier passing runs the baseline is the median of, 5 by default"`
+}
+
+// Settings are the settings specific to a single environment
+type Settings struct {
+	Defaults Defaults `yaml:"defaults,omitempty" json:"defaults,omitempty" description:"Defaults of this environment, over the root defaults"`
+	TLS TLS `yaml:"tls,omitempty" json:"tls,omitempty" description:"Certificates of this environment"`
+	Transport TransportSettings `yaml:"transport,omitempty" json:"transport,omitempty" description:"Proxy, HTTP version and connections of this environment"`
+}
+
+// EnvironmentSettings are the settings of each of the 3 environments
+type EnvironmentSettings struct {
+	Development Settings `yaml:"development,omitempty" json:"development,omitempty" description:"Settings of the development environment"`
+	Staging Settings `yaml:"staging,omitempty" json:"staging,omitempty" description:"Settings of the staging environment"`
+	Production Settings `yaml:"production,omitempty" json:"production,omitempty" description:"Settings of the production environment"`
+}
+
+// GraphQL is a GraphQL operation sent by a step, a response carrying
+// errors fails the step even with a 200 status code
+type GraphQL struct {
+	Query string `yaml:"query" json:"query" description:"Query or mutation document" required:"true"`
+	Variables map[string]any `yaml:"variables,omitempty" json:"variables,omitempty" description:"Variables of the operation"`
+	OperationName string `yaml:"operationName,omitempty" json:"operationName,omitempty" description:"Operation to run when the document holds several"`
+}
+
+// GRPC is a unary gRPC call. The method is resolved through the proto
+// files when given, otherwise through the reflection service of the server
+type GRPC struct {
+	Service string `yaml:"service" json:"service" description:"Fully qualified service name, such as users.v1.UserService" required:"true"`
+	Method string `yaml:"method" json:"method" description:"Method of the service" required:"true"`
+	Message any `yaml:"message,omitempty" json:"message,omitempty" description:"Request message written as json"`
+	Address string `yaml:"address,omitempty" json:"address,omitempty" description:"host:port of the server, the host of the base url by default"`
+	Protos []string `yaml:"protos,omitempty" json:"protos,omitempty" description:"Proto files describing the service, relative to the configuration"`
+	ImportPaths []string `yaml:"importPaths,omitempty" json:"importPaths,omitempty" description:"Directories the proto imports are looked up in, relative to the configuration"`
+}
+
+// Stream scripts a websocket or server-sent events step. The messages are
+// sent once connected, then the expected messages are awaited in order
+// until the step timeout, received messages that match none are skipped
+type Stream struct {
+	Send []any `yaml:"send,omitempty" json:"send,omitempty" description:"Messages sent over a websocket, strings as they are and other values as json"`
+	Expect []StreamExpectation `yaml:"expect,omitempty" json:"expect,omitempty" description:"Messages to wait for, in order"`
+}
+
+// StreamExpectation is a message a stream step waits for
+type StreamExpectation struct {
+	Match any `yaml:"match,omitempty" json:"match,omitempty" description:"Value the message has to match, objects only need the written keys"`
+	Event string `yaml:"event,omitempty" json:"event,omitempty" description:"Event name a server-sent event must have"`
+	Capture map[string]string `yaml:"capture,omitempty" json:"capture,omitempty" description:"Variables to store, mapped to dot separated paths of the message"`
+}
+
+// WaitUntil polls a step until a condition on its response holds, such as
+// `status == 200 && body.job.status == "done"`
+type WaitUntil struct {
+	Condition string `yaml:"condition" json:"condition" description:"Expression over status, body, headers and the variables" required:"true"`
+	Interval string `yaml:"interval,omitempty" json:"interval,omitempty" description:"Time between two polls, 1s by default" format:"duration"`
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty" description:"Time the condition has to hold within, 30s by default" format:"duration"`
 }
 
 // PipelineBody are all the elements that are sent by the
 // user from the configuration file
 type PipelineBody struct {
-	Method string `yaml:"method" json:"method"`
-	Endpoint string `yaml:"endpoint" json:"endpoint"`
-	Body any `yaml:"body" json:"body"`
-	Headers any `yaml:"headers" json:"headers"`
-	ExpectedStatusCode int `yaml:"expectedStatusCode" json:"expectedStatusCode"`
-	ExpectedBody any `yaml:"expectedBody" json:"expectedBody"`
+	Name string `yaml:"name,omitempty" json:"name,omitempty" description:"Name used to select the step with --step"`
+	Kind string `yaml:"kind,omitempty" json:"kind,omitempty" description:"Protocol of the step, http by default" enum:"http,grpc,websocket,sse"`
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty" description:"Tags used to select the step with --tags"`
+	Method string `yaml:"method,omitempty" json:"method,omitempty" description:"HTTP method, GET by default"`
+	Extends string `yaml:"extends,omitempty" json:"extends,omitempty" description:"Name of the template this step is based on"`
+	Endpoint string `yaml:"endpoint" json:"endpoint" description:"Path appended to the base url of the environment, required unless inherited from a template"`
+	Body any `yaml:"body,omitempty" json:"body,omitempty" description:"Request body, files are written as {file: path} relative to the file declaring the body"`
+	GRPC *GRPC `yaml:"grpc,omitempty" json:"grpc,omitempty" description:"Unary gRPC call made by a step of kind grpc"`
+	Stream *Stream `yaml:"stream,omitempty" json:"stream,omitempty" description:"Messages sent and awaited by a step of kind websocket or sse"`
+	GraphQL *GraphQL `yaml:"graphql,omitempty" json:"graphql,omitempty" description:"GraphQL operation posted as the body of the request"`
+	BodyType string `yaml:"bodyType,omitempty" json:"bodyType,omitempty" description:"Encoding of the body, json when a body is given" enum:"json,form,multipart,raw,binary,none"`
+	Headers any `yaml:"headers,omitempty" json:"headers,omitempty" description:"Request headers"`
+	Query map[string]any `yaml:"query,omitempty" json:"query,omitempty" description:"Query parameters, a list value repeats the parameter"`
+	Cookies map[string]any `yaml:"cookies,omitempty" json:"cookies,omitempty" description:"Cookies sent with the request, beside the ones of the cookie jar"`
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty" description:"Time the request may take, such as 5s or 500ms" format:"duration"`
+	FollowRedirects *bool `yaml:"followRedirects,omitempty" json:"followRedirects,omitempty" description:"Follows the redirects of the response, true by default"`
+	ExpectedStatusCode int `yaml:"expectedStatusCode,omitempty" json:"expectedStatusCode,omitempty" description:"Status code the response must have"`
+	ExpectedBody any `yaml:"expectedBody,omitempty" json:"expectedBody,omitempty" description:"Body the response must have"`
+	ExpectedRedirects []string `yaml:"expectedRedirects,omitempty" json:"expectedRedirects,omitempty" description:"Urls or paths the request is redirected to, in order"`
+	Assert []string `yaml:"assert,omitempty" json:"assert,omitempty" description:"Expressions that all have to hold once the response arrives, such as duration.ttfb < 300ms"`
+	ExpectedCookies map[string]string `yaml:"expectedCookies,omitempty" json:"expectedCookies,omitempty" description:"Cookies the response must set, mapped to their value, an empty value only needs the cookie to be set"`
+	Capture map[string]string `yaml:"capture,omitempty" json:"capture,omitempty" description:"Variables to store, mapped to dot separated paths of the response body"`
+	If string `yaml:"if,omitempty" json:"if,omitempty" description:"Expression the step only runs when true, over the variables and the previous response"`
+	ForEach string `yaml:"forEach,omitempty" json:"forEach,omitempty" description:"Expression giving an array, the step runs once for each of its items"`
+	As string `yaml:"as,omitempty" json:"as,omitempty" description:"Variable holding the current item of forEach, item by default"`
+	WaitUntil *WaitUntil `yaml:"waitUntil,omitempty" json:"waitUntil,omitempty" description:"Condition the step is hit again until, for asynchronous APIs"`
+	PreRequest string `yaml:"preRequest,omitempty" json:"preRequest,omitempty" description:"Starlark script run before the request is sent, it can change request and vars"`
+	PostResponse string `yaml:"postResponse,omitempty" json:"postResponse,omitempty" description:"Starlark script run once the response arrives, it can change response and vars and fail() the step"`
+	Dir string `yaml:"-" json:"-"`
+}
+
+// Hooks are the steps run around the steps of a pipeline, or of every
+// pipeline when set at the root. The after hooks run even when a step
+// failed, so they can always clean up
+type Hooks struct {
+	BeforeAll []PipelineBody `yaml:"beforeAll,omitempty" json:"beforeAll,omitempty" description:"Steps run once before the others"`
+	AfterAll []PipelineBody `yaml:"afterAll,omitempty" json:"afterAll,omitempty" description:"Steps run once after the others, even when one failed"`
+	BeforeEach []PipelineBody `yaml:"beforeEach,omitempty" json:"beforeEach,omitempty" description:"Steps run before every step"`
+	AfterEach []PipelineBody `yaml:"afterEach,omitempty" json:"afterEach,omitempty" description:"Steps run after every step, even when it failed"`
+}
+
+// Pipeline is a custom pipeline, written either as a bare list of steps or
+// as an object reading the rows of a data file, the steps then run once per
+// row, or setting hooks of its own
+type Pipeline struct {
+	Data string `yaml:"data,omitempty" json:"data,omitempty" description:"csv, json or yaml file whose rows the steps run once for, columns become variables, relative to the file declaring it"`
+	Steps []PipelineBody `yaml:"steps" json:"steps" required:"true" description:"Steps run in a sequence"`
+	Hooks `yaml:",inline"`
+	Dir string `yaml:"-" json:"-"`
 }
 
 // Structure defines the overall structure of the json or yaml
 // configuration file
 type Structure struct {
-	BaseURL Environments `yaml:"baseUrl" json:"baseUrl"`
-	Credentials Credentials `yaml:"credentials" json:"credentials"`
-	LoginDetails LoginDetails `yaml:"loginDetails" json:"loginDetails"`
-	PipelineBody []PipelineBody `yaml:"current_pipeline" json:"current_pipeline"`
-	CustomPipelines map[string]interface{} `yaml:"custom_pipelines" json:"custom_pipelines"`
-	ActiveURL string
-	ActiveEnvironment string
+	Schema string `yaml:"$schema,omitempty" json:"$schema,omitempty" description:"JSON Schema of the file, used by editors"`
+	Include any `yaml:"include,omitempty" json:"include,omitempty" description:"Path or list of paths and globs of json/yaml files merged beneath this one"`
+	BaseURL Environments `yaml:"baseUrl" json:"baseUrl" description:"Base url of every environment"`
+	Credentials Credentials `yaml:"credentials" json:"credentials" description:"Login body of every environment"`
+	LoginDetails LoginDetails `yaml:"loginDetails" json:"loginDetails" description:"How to log in and where to find the token"`
+	PipelineBody []PipelineBody `yaml:"current_pipeline,omitempty" json:"current_pipeline,omitempty" description:"Steps run by default"`
+	CustomPipelines map[string]Pipeline `yaml:"custom_pipelines,omitempty" json:"custom_pipelines,omitempty" description:"Named lists of steps run with --pipeline"`
+	Variables map[string]any `yaml:"variables,omitempty" json:"variables,omitempty" description:"Values available to {{variable}} placeholders"`
+	Templates map[string]PipelineBody `yaml:"templates,omitempty" json:"templates,omitempty" description:"Partial steps that steps build upon with extends"`
+	Defaults Defaults `yaml:"defaults,omitempty" json:"defaults,omitempty" description:"Headers, query parameters and timeout of every request"`
+	Environments EnvironmentSettings `yaml:"environments,omitempty" json:"environments,omitempty" description:"Settings specific to each environment"`
+	Cookies CookieSettings `yaml:"cookieJar,omitempty" json:"cookieJar,omitempty" description:"How the cookies set by responses are kept"`
+	Baseline Baseline `yaml:"baseline,omitempty" json:"baseline,omitempty" description:"When a step counts as slower than in the earlier runs"`
+	Hooks `yaml:",inline"`
+	ActiveURL string `yaml:"-" json:"-"`
+	ActiveSocket string `yaml:"-" json:"-"`
+	ActiveEnvironment string `yaml:"-" json:"-"`
+	ConfigDir string `yaml:"-" json:"-"`
+	Previous *APIResponse `yaml:"-" json:"-"`
+	ActivePipeline string `yaml:"-" json:"-"`
+	ActiveIteration string `yaml:"-" json:"-"`
+	ActiveHook string `yaml:"-" json:"-"`
+	Jar *CookieJar `yaml:"-" json:"-"`
+	Verbose bool `yaml:"-" json:"-"`
+	Transport http.RoundTripper `yaml:"-" json:"-"`
+	Results []StepResult `yaml:"-" json:"-"`
+}
+
+// EnvironmentNames are the environments a configuration file can define
+var EnvironmentNames = []string{"development", "staging", "production"}
+
+// URL returns the base url of an environment by its name
+func (e Environments) URL(env string) (string, bool) {
+	switch env {
+	case "development": return e.Development, true
+	case "staging": return e.Staging, true
+	case "production": return e.Production, true
+	}
+	return "", false
+}
+
+// SelectEnvironment makes the given environment the active one. A unix://
+// base url has its requests sent over the unix socket it names
+func (s *Structure) SelectEnvironment(env string) error {
+	url, exists := s.BaseURL.URL(env)
+	if !exists { return fmt.Errorf("No such environment exists: %s, use one of development, staging or production", env) }
+	if url == "" { return fmt.Errorf("Environment %s is not defined, baseUrl.%s is missing", env, env) }
+
+	s.ActiveURL, s.ActiveSocket = url, ""
+	if strings.HasPrefix(url, "unix://") { s.ActiveSocket, s.ActiveURL = SplitSocket(url) }
+	s.ActiveEnvironment = env
+	return nil
+}
+
+// SplitSocket splits a unix:// url into the path of its socket, ending with
+// the first segment named *.sock or else with the whole path, and the http
+// url the path left over is the base of
+func SplitSocket(url string) (string, string) {
+	path := strings.TrimPrefix(url, "unix://")
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasSuffix(segment, ".sock") {
+			return strings.Join(segments[:i + 1], "/"), "http://localhost" + strings.TrimSuffix("/" + strings.Join(segments[i + 1:], "/"), "/")
+		}
+	}
+	return path, "http://localhost"
 }
 
 // FileReaderStrategy allows the program to change it's behaviour
@@ -79,6 +292,7 @@ type FileReaderStrategy interface {
 	CallCurrentPipeline(fileContents *Structure)
 	CallCustomPipelines(fileContents *Structure)
 	CallSingleCustomPipeline(fileContents *Structure, pipelineKey string)
+	CallFilteredSteps(fileContents *Structure, filter StepFilter)
 }
 
 // FileReaderContext for reading file instructions
@@ -98,7 +312,7 @@ func (c *FileReaderContext) ReadInstructions(filepath string) (*Structure, error
 	}
 
 	structure, err := c.strategy.ReadInstructions(filepath)
-	if err != nil { return nil, fmt.Errorf("Could not call strategy") }
+	if err != nil { return nil, err }
 
 	return structure, nil
 }
@@ -131,4 +345,9 @@ func (c *FileReaderContext) CallSingleCustomPipeline(fileContents *Structure, pi
 	c.strategy.CallSingleCustomPipeline(fileContents, pipelineKey)
 }
 
+// CallFilteredSteps calls only the steps selected by the filter delegates the reading task to the strategy
+func (c *FileReaderContext) CallFilteredSteps(fileContents *Structure, filter StepFilter) {
+	if c.strategy == nil { fmt.Println("strategy not set"); return }
 
+	c.strategy.CallFilteredSteps(fileContents, filter)
+}
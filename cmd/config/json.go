@@ -0,0 +1,281 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonParser is a small recursive descent parser keeping the position of
+// every value, which encoding/json does not expose
+type jsonParser struct {
+	file string
+	data []byte
+	offset int
+	relaxed bool
+
+	// the line and column reached so far, positions only ever move forward
+	scanned int
+	line int
+	column int
+}
+
+// ParseJSON parses a strict json document into a tree of nodes
+func ParseJSON(file string, data []byte) (*Node, error) {
+	return (&jsonParser{file: file, data: data}).parse()
+}
+
+// ParseJSONC parses json allowing what people write in hand edited files:
+// `//` and `/* */` comments, trailing commas and, as in JSON5, unquoted keys,
+// single quoted strings, hexadecimal numbers and leading plus signs or dots
+func ParseJSONC(file string, data []byte) (*Node, error) {
+	return (&jsonParser{file: file, data: data, relaxed: true}).parse()
+}
+
+// parse reads the whole document as a single value
+func (p *jsonParser) parse() (*Node, error) {
+	p.skipSpace()
+	root, err := p.value()
+	if err != nil { return nil, err }
+
+	p.skipSpace()
+	if p.offset < len(p.data) { return nil, p.errorf("unexpected %s after the end of the document", p.describe()) }
+
+	return root, nil
+}
+
+// position turns an offset into a line and a column, carrying on from the
+// last position asked for so a document is scanned only once
+func (p *jsonParser) position(offset int) (int, int) {
+	if p.line == 0 || offset < p.scanned { p.scanned, p.line, p.column = 0, 1, 1 }
+
+	for ; p.scanned < offset && p.scanned < len(p.data); p.scanned++ {
+		if c := p.data[p.scanned]; c == '\n' {
+			p.line, p.column = p.line + 1, 1
+		} else if utf8.RuneStart(c) {
+			p.column++
+		}
+	}
+	return p.line, p.column
+}
+
+// node creates a node starting at the given offset
+func (p *jsonParser) node(kind Kind, offset int) *Node {
+	line, column := p.position(offset)
+	return &Node{Kind: kind, File: p.file, Line: line, Column: column}
+}
+
+// errorf creates an error at the current offset
+func (p *jsonParser) errorf(format string, args ...any) *Error {
+	n := p.node(Null, p.offset)
+	err := errorAt(n, format, args...)
+
+	if !p.relaxed && p.offset < len(p.data) && p.data[p.offset] == '/' {
+		err.Message += ", comments are only allowed in .jsonc and .json5 files"
+	}
+	return err
+}
+
+// describe names the character at the current offset for error messages
+func (p *jsonParser) describe() string {
+	if p.offset >= len(p.data) { return "end of file" }
+	r, _ := utf8.DecodeRune(p.data[p.offset:])
+	return strconv.QuoteRune(r)
+}
+
+func (p *jsonParser) skipSpace() {
+	for p.offset < len(p.data) {
+		switch p.data[p.offset] {
+		case ' ', '\t', '\n', '\r': p.offset++
+		case '/':
+			if !p.relaxed || !p.skipComment() { return }
+		default: return
+		}
+	}
+}
+
+// skipComment moves past a `//` or `/* */` comment, telling if there was one
+func (p *jsonParser) skipComment() bool {
+	if bytes.HasPrefix(p.data[p.offset:], []byte("//")) {
+		end := bytes.IndexByte(p.data[p.offset:], '\n')
+		if end < 0 { p.offset = len(p.data) } else { p.offset += end }
+		return true
+	}
+	if bytes.HasPrefix(p.data[p.offset:], []byte("/*")) {
+		end := bytes.Index(p.data[p.offset+2:], []byte("*/"))
+		if end < 0 { p.offset = len(p.data) } else { p.offset += end + 4 }
+		return true
+	}
+	return false
+}
+
+// value parses whichever value starts at the current offset
+func (p *jsonParser) value() (*Node, error) {
+	if p.offset >= len(p.data) { return nil, p.errorf("unexpected end of file, expected a value") }
+
+	switch c := p.data[p.offset]; {
+	case c == '{': return p.object()
+	case c == '[': return p.array()
+	case c == '"' || (p.relaxed && c == '\''): return p.string()
+	case c == '-' || (c >= '0' && c <= '9') || (p.relaxed && (c == '+' || c == '.')): return p.number()
+	case c == 't': return p.literal("true", Bool, true)
+	case c == 'f': return p.literal("false", Bool, false)
+	case c == 'n': return p.literal("null", Null, nil)
+	}
+	return nil, p.errorf("unexpected %s, expected a value", p.describe())
+}
+
+func (p *jsonParser) object() (*Node, error) {
+	n := p.node(Object, p.offset)
+	n.Fields = map[string]*Node{}
+	p.offset++
+
+	p.skipSpace()
+	if p.offset < len(p.data) && p.data[p.offset] == '}' { p.offset++; return n, nil }
+
+	for {
+		p.skipSpace()
+		if p.offset < len(p.data) && p.data[p.offset] == '}' {
+			if p.relaxed { p.offset++; return n, nil }
+			return nil, p.errorf("trailing comma before '}'")
+		}
+
+		key, err := p.key()
+		if err != nil { return nil, err }
+		if n.Fields[key.Value.(string)] != nil { return nil, errorAt(key, "duplicate key %q", key.Value) }
+
+		p.skipSpace()
+		if p.offset >= len(p.data) || p.data[p.offset] != ':' { return nil, p.errorf("unexpected %s, expected ':' after key", p.describe()) }
+		p.offset++
+
+		p.skipSpace()
+		value, err := p.value()
+		if err != nil { return nil, err }
+		n.set(key, value)
+
+		p.skipSpace()
+		if p.offset >= len(p.data) { return nil, p.errorf("unexpected end of file, expected ',' or '}'") }
+		if p.data[p.offset] == '}' { p.offset++; return n, nil }
+		if p.data[p.offset] != ',' { return nil, p.errorf("unexpected %s, expected ',' or '}'", p.describe()) }
+		p.offset++
+	}
+}
+
+func (p *jsonParser) array() (*Node, error) {
+	n := p.node(Array, p.offset)
+	n.Items = []*Node{}
+	p.offset++
+
+	p.skipSpace()
+	if p.offset < len(p.data) && p.data[p.offset] == ']' { p.offset++; return n, nil }
+
+	for {
+		p.skipSpace()
+		if p.offset < len(p.data) && p.data[p.offset] == ']' {
+			if p.relaxed { p.offset++; return n, nil }
+			return nil, p.errorf("trailing comma before ']'")
+		}
+
+		item, err := p.value()
+		if err != nil { return nil, err }
+		n.Items = append(n.Items, item)
+
+		p.skipSpace()
+		if p.offset >= len(p.data) { return nil, p.errorf("unexpected end of file, expected ',' or ']'") }
+		if p.data[p.offset] == ']' { p.offset++; return n, nil }
+		if p.data[p.offset] != ',' { return nil, p.errorf("unexpected %s, expected ',' or ']'", p.describe()) }
+		p.offset++
+	}
+}
+
+// key parses an object key, which relaxed json allows to be an unquoted identifier
+func (p *jsonParser) key() (*Node, error) {
+	if p.offset < len(p.data) && (p.data[p.offset] == '"' || (p.relaxed && p.data[p.offset] == '\'')) { return p.string() }
+	if !p.relaxed { return nil, p.errorf("unexpected %s, expected a quoted key", p.describe()) }
+
+	n := p.node(String, p.offset)
+	start := p.offset
+	for p.offset < len(p.data) && isIdentifier(p.data[p.offset]) { p.offset++ }
+	if start == p.offset { return nil, p.errorf("unexpected %s, expected a key", p.describe()) }
+
+	n.Value = string(p.data[start:p.offset])
+	return n, nil
+}
+
+func isIdentifier(c byte) bool {
+	return c == '_' || c == '$' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (p *jsonParser) string() (*Node, error) {
+	n := p.node(String, p.offset)
+	quote := p.data[p.offset]
+	start := p.offset
+	p.offset++
+
+	// finding the closing quote, escapes are decoded by encoding/json
+	for p.offset < len(p.data) {
+		switch p.data[p.offset] {
+		case '\\': p.offset += 2; continue
+		case '\n': return nil, p.errorf("unterminated string")
+		case quote:
+			p.offset++
+			raw := p.data[start:p.offset]
+			if quote == '\'' { raw = requote(raw) }
+
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil { return nil, errorAt(n, "invalid string: %s", err.Error()) }
+			n.Value = value
+			return n, nil
+		}
+		p.offset++
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+// requote turns a single quoted string into a double quoted one
+func requote(raw []byte) []byte {
+	inner := string(raw[1:len(raw)-1])
+	inner = strings.ReplaceAll(inner, `\'`, `'`)
+	inner = strings.ReplaceAll(inner, `"`, `\"`)
+	inner = strings.ReplaceAll(inner, `\\"`, `\"`)
+	return []byte(`"` + inner + `"`)
+}
+
+func (p *jsonParser) number() (*Node, error) {
+	n := p.node(Number, p.offset)
+	start := p.offset
+
+	for p.offset < len(p.data) {
+		c := p.data[p.offset]
+		if !(c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' || (c >= '0' && c <= '9')) && !(p.relaxed && isIdentifier(c)) { break }
+		p.offset++
+	}
+
+	var value float64
+	raw := string(p.data[start:p.offset])
+	if p.relaxed {
+		parsed, err := strconv.ParseFloat(strings.TrimPrefix(raw, "+"), 64)
+		if err != nil {
+			integer, intErr := strconv.ParseInt(strings.TrimPrefix(raw, "+"), 0, 64)
+			if intErr != nil { return nil, errorAt(n, "invalid number %q", raw) }
+			parsed = float64(integer)
+		}
+		n.Value = parsed
+		return n, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil { return nil, errorAt(n, "invalid number %q", raw) }
+	n.Value = value
+	return n, nil
+}
+
+func (p *jsonParser) literal(word string, kind Kind, value any) (*Node, error) {
+	n := p.node(kind, p.offset)
+	if p.offset + len(word) > len(p.data) || string(p.data[p.offset:p.offset+len(word)]) != word {
+		return nil, p.errorf("unexpected %s, expected a value", p.describe())
+	}
+	p.offset += len(word)
+	n.Value = value
+	return n, nil
+}
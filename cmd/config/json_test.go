@@ -0,0 +1,95 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJSONPositions(t *testing.T) {
+	data := "{\n  \"baseUrl\": {\n    \"development\": \"http://localhost\"\n  },\n  \"ümlaut\": [1, true, null]\n}"
+	root, err := ParseJSON("api.json", []byte(data))
+	if err != nil { t.Fatalf("ParseJSON() returned %v", err) }
+
+	tests := []struct {
+		name string
+		node *Node
+		line int
+		column int
+	}{
+		{"root", root, 1, 1},
+		{"key", root.Key("baseUrl"), 2, 3},
+		{"object value", root.Field("baseUrl"), 2, 14},
+		{"nested value", root.Field("baseUrl").Field("development"), 3, 20},
+		{"key after multibyte", root.Key("ümlaut"), 5, 3},
+		{"array", root.Field("ümlaut"), 5, 13},
+		{"item after multibyte", root.Field("ümlaut").Items[2], 5, 23},
+	}
+
+	for _, test := range tests {
+		if test.node == nil { t.Errorf("%s: node not found", test.name); continue }
+		if test.node.Line != test.line || test.node.Column != test.column { t.Errorf("%s at %d:%d, want %d:%d", test.name, test.node.Line, test.node.Column, test.line, test.column) }
+	}
+}
+
+func TestParseJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"missing comma", "{\n  \"a\": 1\n  \"b\": 2\n}", "api.json:3:3: unexpected '\"', expected ',' or '}'"},
+		{"trailing comma", "{\"a\": [1, 2,]}", "api.json:1:13: trailing comma before ']'"},
+		{"comment", "{\n  // note\n  \"a\": 1\n}", "api.json:2:3: unexpected '/', expected a quoted key, comments are only allowed in .jsonc and .json5 files"},
+		{"duplicate key", "{\"a\": 1,\n \"a\": 2}", "api.json:2:2: duplicate key \"a\""},
+		{"unterminated string", "{\"a\": \"b\n}", "api.json:1:9: unterminated string"},
+		{"end of file", "[1, 2", "api.json:1:6: unexpected end of file, expected ',' or ']'"},
+		{"after the document", "{} {}", "api.json:1:4: unexpected '{' after the end of the document"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseJSON("api.json", []byte(test.data))
+			if err == nil || err.Error() != test.want { t.Errorf("ParseJSON() returned %v, want %s", err, test.want) }
+		})
+	}
+}
+
+func TestParseJSONC(t *testing.T) {
+	data := "{\n  // comment\n  name: 'single',\n  /* block */ hex: 0x10,\n  plus: +1.5,\n  list: [1, 2,],\n}"
+	root, err := ParseJSONC("api.jsonc", []byte(data))
+	if err != nil { t.Fatalf("ParseJSONC() returned %v", err) }
+
+	if got := root.Field("name").Value; got != "single" { t.Errorf("name = %v, want single", got) }
+	if got := root.Field("hex").Value; got != float64(16) { t.Errorf("hex = %v, want 16", got) }
+	if got := root.Field("plus").Value; got != 1.5 { t.Errorf("plus = %v, want 1.5", got) }
+	if got := len(root.Field("list").Items); got != 2 { t.Errorf("list has %d items, want 2", got) }
+	if hex := root.Key("hex"); hex.Line != 4 || hex.Column != 15 { t.Errorf("hex at %d:%d, want 4:15", hex.Line, hex.Column) }
+}
+
+// a long single line document, as minified json is, keeps its positions right
+func TestParseJSONLongLine(t *testing.T) {
+	items := strings.Repeat("1, ", 20000)
+	root, err := ParseJSON("api.json", []byte("[" + items + "\"last\"]"))
+	if err != nil { t.Fatalf("ParseJSON() returned %v", err) }
+
+	last := root.Items[len(root.Items) - 1]
+	if last.Line != 1 || last.Column != len(items) + 2 { t.Errorf("last item at %d:%d, want 1:%d", last.Line, last.Column, len(items) + 2) }
+}
+
+func TestParseJSONCRelaxed(t *testing.T) {
+	root, err := ParseJSONC("api.json5", []byte("{a: 'it\\'s', b: .5, c: -0x1F, 'd': [1, /* two */ 2,], e: \"x\" // end\n}"))
+	if err != nil { t.Fatalf("ParseJSONC() returned %v", err) }
+	if got, want := root.Interface(), map[string]any{"a": "it's", "b": 0.5, "c": float64(-31), "d": []any{float64(1), float64(2)}, "e": "x"}; !reflect.DeepEqual(got, want) { t.Errorf("ParseJSONC() = %v, want %v", got, want) }
+
+	// relaxed does not mean anything goes
+	for _, bad := range [][2]string{
+		{"{a: /* open", "api.json5:1:12: unexpected end of file, expected a value"},
+		{"{a: 1,,}", "api.json5:1:7: unexpected ',', expected a key"},
+		{"{a: 'x\n'}", "api.json5:1:7: unterminated string"},
+		{"{a: Infinity}", "api.json5:1:5: unexpected 'I', expected a value"},
+	} {
+		_, err := ParseJSONC("api.json5", []byte(bad[0]))
+		if err == nil || err.Error() != bad[1] { t.Errorf("ParseJSONC(%q) returned %v, want %s", bad[0], err, bad[1]) }
+	}
+}
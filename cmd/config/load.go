@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Parser turns the raw contents of a file into a tree of nodes
+type Parser func(file string, data []byte) (*Node, error)
+
+// Extensions are the configuration file types picked up in directory mode
+var Extensions = []string{".json", ".jsonc", ".json5", ".yaml", ".yml", ".toml"}
+
+// ParserFor picks the parser of a file by its extension
+func ParserFor(path string) (Parser, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json": return ParseJSON, nil
+	case ".jsonc", ".json5": return ParseJSONC, nil
+	case ".yaml", ".yml": return ParseYAML, nil
+	case ".toml": return ParseTOML, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported file format, use json, jsonc, json5, yaml, yml or toml", DisplayName(path))
+}
+
+// DiscoveryNames are the file names looked for when no --file is given,
+// `api.*` are the names used by older releases
+var DiscoveryNames = []string{
+	"apee-i.json", "apee-i.jsonc", "apee-i.json5", "apee-i.yaml", "apee-i.yml", "apee-i.toml",
+	"api.json", "api.yaml", "api.yml",
+}
+
+// Discover walks up from the directory looking for a configuration file
+func Discover(directory string) (string, error) {
+	directory, err := filepath.Abs(directory)
+	if err != nil { return "", err }
+
+	for {
+		for _, name := range DiscoveryNames {
+			candidate := filepath.Join(directory, name)
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() { return candidate, nil }
+		}
+
+		parent := filepath.Dir(directory)
+		if parent == directory { break }
+		directory = parent
+	}
+
+	return "", fmt.Errorf("no configuration file found, looked for apee-i.{json,yaml,yml,toml} from the working directory up, use --file or apee-i init")
+}
+
+// IsPattern tells if a --file value is a directory or a glob rather than a single file
+func IsPattern(path string) bool {
+	if strings.ContainsAny(path, "*?[") { return true }
+
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// Expand lists the configuration files a --file value stands for: the file
+// itself, every configuration file of a directory or the matches of a glob
+func Expand(path string) ([]string, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		files := []string{}
+		for _, ext := range Extensions {
+			matches, _ := filepath.Glob(filepath.Join(path, "*" + ext))
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+		if len(files) == 0 { return nil, fmt.Errorf("%s: no configuration files found in directory", DisplayName(path)) }
+		return files, nil
+	}
+
+	if !strings.ContainsAny(path, "*?[") { return []string{path}, nil }
+
+	files, err := filepath.Glob(path)
+	if err != nil { return nil, fmt.Errorf("invalid --file pattern %q: %s", path, err.Error()) }
+	if len(files) == 0 { return nil, fmt.Errorf("no configuration files match %q", path) }
+	return files, nil
+}
+
+// loader reads configuration files while following their includes
+type loader struct {
+	loaded map[string]bool
+	stack []string
+}
+
+// Load reads the configuration at path along with everything it includes.
+// The path may be a single file parsed with the given parser, or a directory
+// or glob whose files are merged as peers, each parsed by its extension
+func Load(path string, parse Parser) (*Node, error) {
+	l := &loader{loaded: map[string]bool{}}
+	if !IsPattern(path) { return l.file(path, parse) }
+
+	files, err := Expand(path)
+	if err != nil { return nil, err }
+
+	var root *Node
+	for _, file := range files {
+		parse, err := ParserFor(file)
+		if err != nil { return nil, err }
+
+		n, err := l.file(file, parse)
+		if err != nil { return nil, err }
+		if root, err = merge(root, n, false, ""); err != nil { return nil, err }
+	}
+	return root, nil
+}
+
+// file reads a single file, merges its includes beneath it and returns nil
+// when the file has already been loaded through another include
+func (l *loader) file(path string, parse Parser) (*Node, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil { return nil, err }
+
+	for _, parent := range l.stack {
+		if parent == absolute { return nil, fmt.Errorf("%s: include cycle: %s", DisplayName(path), l.cycle(absolute)) }
+	}
+	if l.loaded[absolute] { return nil, nil }
+	l.loaded[absolute] = true
+
+	data, err := os.ReadFile(absolute)
+	if err != nil { return nil, fmt.Errorf("Could not open file %s", DisplayName(path)) }
+
+	root, err := parse(DisplayName(absolute), data)
+	if err != nil { return nil, err }
+	if root.Kind != Object { return root, nil }
+
+	include := root.Field("include")
+	if include == nil { return root, nil }
+
+	// the include key is resolved here and never reaches the decoder
+	patterns, err := includePatterns(include)
+	if err != nil { return nil, err }
+	delete(root.Fields, "include")
+	for i, key := range root.Keys {
+		if key.Value == "include" { root.Keys = append(root.Keys[:i:i], root.Keys[i+1:]...); break }
+	}
+
+	l.stack = append(l.stack, absolute)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	// included files are peers to each other, the including file wins over them
+	var base *Node
+	for _, pattern := range patterns {
+		target := pattern.Value.(string)
+		if !filepath.IsAbs(target) { target = filepath.Join(filepath.Dir(absolute), target) }
+
+		files, err := filepath.Glob(target)
+		if err != nil || len(files) == 0 { return nil, errorAt(pattern, "include %q matches no file", pattern.Value) }
+		sort.Strings(files)
+
+		for _, file := range files {
+			parse, err := ParserFor(file)
+			if err != nil { return nil, errorAt(pattern, "%s", err.Error()) }
+
+			n, err := l.file(file, parse)
+			if err != nil { return nil, err }
+			if base, err = merge(base, n, false, ""); err != nil { return nil, err }
+		}
+	}
+
+	return merge(base, root, true, "")
+}
+
+// cycle describes the chain of includes leading back to a file
+func (l *loader) cycle(absolute string) string {
+	chain := []string{}
+	for _, parent := range l.stack { chain = append(chain, DisplayName(parent)) }
+	return strings.Join(append(chain, DisplayName(absolute)), " -> ")
+}
+
+// includePatterns accepts a single path or a list of paths and globs
+func includePatterns(include *Node) ([]*Node, error) {
+	if include.Kind == String { return []*Node{include}, nil }
+	if include.Kind != Array { return nil, errorAt(include, "include: expected string or array, got %s", describe(include)) }
+
+	for _, item := range include.Items {
+		if item.Kind != String { return nil, errorAt(item, "include: expected string, got %s", describe(item)) }
+	}
+	return include.Items, nil
+}
+
+// merge combines two trees, objects are merged key by key and the steps of
+// `current_pipeline` are appended. Any other value set on both sides is taken
+// from top when override is set, otherwise it is a conflict unless both agree
+func merge(base *Node, top *Node, override bool, path string) (*Node, error) {
+	if base == nil { return top, nil }
+	if top == nil { return base, nil }
+
+	if base.Kind == Object && top.Kind == Object {
+		out := &Node{Kind: Object, Fields: map[string]*Node{}, File: top.File, Line: top.Line, Column: top.Column}
+		for _, key := range base.Keys { out.set(key, base.Fields[key.Value.(string)]) }
+
+		for _, key := range top.Keys {
+			name := key.Value.(string)
+			merged, err := merge(out.Fields[name], top.Fields[name], override, join(path, name))
+			if err != nil { return nil, err }
+			if out.Fields[name] == nil { out.set(key, merged) } else { out.Fields[name] = merged }
+		}
+		return out, nil
+	}
+
+	if path == "current_pipeline" && base.Kind == Array && top.Kind == Array {
+		out := &Node{Kind: Array, File: base.File, Line: base.Line, Column: base.Column}
+		out.Items = append(append([]*Node{}, base.Items...), top.Items...)
+		return out, nil
+	}
+
+	if override || same(base, top) { return top, nil }
+	return nil, errorAt(top, "%s is also set at %s, files loaded together cannot set the same value", path, base.Position())
+}
+
+// same tells if two scalars hold an identical value
+func same(a *Node, b *Node) bool {
+	return a.Kind == b.Kind && a.Kind != Object && a.Kind != Array && a.Value == b.Value
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// inDirectory writes the files in a temporary directory and moves into it so
+// the positions of the errors are relative to it
+func inDirectory(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil { t.Fatal(err) }
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil { t.Fatal(err) }
+	}
+
+	wd, err := os.Getwd()
+	if err != nil { t.Fatal(err) }
+	if err := os.Chdir(dir); err != nil { t.Fatal(err) }
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestLoadIncludes(t *testing.T) {
+	inDirectory(t, map[string]string{
+		"api.yaml": "include: shared/*.yaml\nvariables: { region: eu }\ncurrent_pipeline:\n  - endpoint: /main\n",
+		"shared/a.yaml": "variables: { region: us, team: core }\ncurrent_pipeline:\n  - endpoint: /a\n",
+		"shared/b.yaml": "include: a.yaml\nvariables: { team: core }\ncurrent_pipeline:\n  - endpoint: /b\n",
+	})
+
+	root, err := Load("api.yaml", ParseYAML)
+	if err != nil { t.Fatalf("Load() returned %v", err) }
+
+	// the including file wins, peers agreeing on a value are fine and a
+	// file included twice is only read once
+	variables := root.Field("variables").Interface()
+	if want := map[string]any{"region": "eu", "team": "core"}; !reflect.DeepEqual(variables, want) { t.Errorf("variables = %v, want %v", variables, want) }
+	if root.Field("include") != nil { t.Errorf("the include key reached the merged configuration") }
+
+	endpoints := []any{}
+	for _, step := range root.Field("current_pipeline").Items { endpoints = append(endpoints, step.Field("endpoint").Value) }
+	if want := []any{"/a", "/b", "/main"}; !reflect.DeepEqual(endpoints, want) { t.Errorf("current_pipeline = %v, want %v", endpoints, want) }
+}
+
+func TestLoadConflicts(t *testing.T) {
+	scenarios := map[string]struct {
+		files map[string]string
+		path string
+		want string
+	}{
+		"included peers": {
+			files: map[string]string{
+				"api.yaml": "include: [a.yaml, b.yaml]\n",
+				"a.yaml": "variables:\n  region: us\n",
+				"b.yaml": "variables:\n  region: eu\n",
+			},
+			path: "api.yaml",
+			want: "b.yaml:2:11: variables.region is also set at a.yaml:2:11, files loaded together cannot set the same value",
+		},
+		"directory": {
+			files: map[string]string{
+				"config/a.json": "{\"loginDetails\": {\"type\": \"JWT\"}}",
+				"config/b.yaml": "loginDetails:\n  type: none\n",
+			},
+			path: "config",
+			want: "config/b.yaml:2:9: loginDetails.type is also set at config/a.json:1:27, files loaded together cannot set the same value",
+		},
+		"object against a value": {
+			files: map[string]string{
+				"a.yaml": "custom_pipelines:\n  users: [{ endpoint: /users }]\n",
+				"b.yaml": "custom_pipelines:\n  users:\n    steps: [{ endpoint: /users }]\n",
+			},
+			path: "*.yaml",
+			want: "b.yaml:3:5: custom_pipelines.users is also set at a.yaml:2:10, files loaded together cannot set the same value",
+		},
+		"cycle": {
+			files: map[string]string{
+				"api.yaml": "include: a.yaml\n",
+				"a.yaml": "include: b.yaml\n",
+				"b.yaml": "include: api.yaml\n",
+			},
+			path: "api.yaml",
+			want: "api.yaml: include cycle: api.yaml -> a.yaml -> b.yaml -> api.yaml",
+		},
+		"no match": {
+			files: map[string]string{"api.yaml": "include:\n  - shared/*.yaml\n"},
+			path: "api.yaml",
+			want: "api.yaml:2:5: include \"shared/*.yaml\" matches no file",
+		},
+	}
+
+	for name, scenario := range scenarios {
+		inDirectory(t, scenario.files)
+		_, err := Load(scenario.path, ParseYAML)
+		if err == nil || err.Error() != scenario.want { t.Errorf("%s: Load() returned %v, want %s", name, err, scenario.want) }
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	inDirectory(t, map[string]string{
+		"project/api.yaml": "",
+		"project/apee-i.toml": "",
+		"project/service/src/main.go": "",
+		"project/service/api.json": "",
+		"project/service/api/readme.md": "",
+	})
+
+	// the closest directory wins, and the apee-i names before the older ones
+	for dir, want := range map[string]string{
+		"project": "project/apee-i.toml",
+		"project/service/src": "project/service/api.json",
+		"project/service/api": "project/service/api.json",
+	} {
+		got, err := Discover(dir)
+		if err != nil { t.Errorf("Discover(%s) returned %v", dir, err); continue }
+		if absolute, _ := filepath.Abs(want); got != absolute { t.Errorf("Discover(%s) = %s, want %s", dir, got, absolute) }
+	}
+
+	// a directory named like a configuration file is not one
+	if err := os.Mkdir("project/service/src/apee-i.json", 0755); err != nil { t.Fatal(err) }
+	if got, _ := Discover("project/service/src"); filepath.Base(got) != "api.json" { t.Errorf("Discover() picked the directory %s", got) }
+}
+
+func TestDiscoverNothing(t *testing.T) {
+	inDirectory(t, map[string]string{"readme.md": ""})
+	if _, err := Discover("."); err == nil { t.Errorf("Discover() found a configuration in an empty tree") }
+}
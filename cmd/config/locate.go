@@ -0,0 +1,64 @@
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// Dir is the absolute directory of the file the node is written in, the
+// paths a node holds are relative to it
+func (n *Node) Dir() string {
+	dir, err := filepath.Abs(filepath.Dir(n.File))
+	if err != nil { return filepath.Dir(n.File) }
+	return dir
+}
+
+// Locate stores on the decoded configuration the directory of the file each
+// step, data file and TLS setting is written in, as included files can sit
+// in other directories
+func Locate(root *Node, fileContents *cmd.Structure) {
+	locateSteps(root.Field("current_pipeline"), fileContents.PipelineBody)
+	locateHooks(root, &fileContents.Hooks)
+
+	environments := map[string]*cmd.Settings{"development": &fileContents.Environments.Development, "staging": &fileContents.Environments.Staging, "production": &fileContents.Environments.Production}
+	for env, settings := range environments {
+		if n := root.Field("environments").Field(env).Field("tls"); n != nil { settings.TLS.Dir = n.Dir() }
+	}
+
+	custom := root.Field("custom_pipelines")
+	if custom == nil { return }
+	for name, n := range custom.Fields {
+		pipeline, ok := fileContents.CustomPipelines[name]
+		if !ok { continue }
+
+		if n.Kind == Array {
+			locateSteps(n, pipeline.Steps)
+		} else {
+			locateSteps(n.Field("steps"), pipeline.Steps)
+			locateHooks(n, &pipeline.Hooks)
+			if data := n.Field("data"); data != nil { pipeline.Dir = data.Dir() }
+		}
+		fileContents.CustomPipelines[name] = pipeline
+	}
+}
+
+// locateHooks locates the steps of the hooks set on a node
+func locateHooks(owner *Node, hooks *cmd.Hooks) {
+	locateSteps(owner.Field("beforeAll"), hooks.BeforeAll)
+	locateSteps(owner.Field("afterAll"), hooks.AfterAll)
+	locateSteps(owner.Field("beforeEach"), hooks.BeforeEach)
+	locateSteps(owner.Field("afterEach"), hooks.AfterEach)
+}
+
+// locateSteps gives every step the directory of its body, which may come
+// from a template written in another file, else the one of the step
+func locateSteps(n *Node, steps []cmd.PipelineBody) {
+	if n == nil || n.Kind != Array { return }
+
+	for i, item := range n.Items {
+		if i >= len(steps) { break }
+		steps[i].Dir = item.Dir()
+		if body := item.Field("body"); body != nil { steps[i].Dir = body.Dir() }
+	}
+}
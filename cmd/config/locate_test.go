@@ -0,0 +1,102 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+func TestLocate(t *testing.T) {
+	inDirectory(t, map[string]string{
+		"api.yaml": header + `include: [shared/steps.yaml, templates/upload.yaml]
+beforeAll:
+  - endpoint: /login
+current_pipeline:
+  - endpoint: /avatar
+    extends: upload
+  - endpoint: /note
+    bodyType: raw
+    body: { file: note.txt }
+`,
+		"shared/steps.yaml": `custom_pipelines:
+  users:
+    - endpoint: /users
+      body: { file: user.json }
+`,
+		"templates/upload.yaml": `templates:
+  upload:
+    method: PUT
+    bodyType: binary
+    body: { file: avatar.png }
+`,
+	})
+
+	root, err := Load("api.yaml", ParseYAML)
+	if err != nil { t.Fatal(err) }
+	if err := Validate(root); err != nil { t.Fatal(err) }
+	fileContents := &cmd.Structure{}
+	if err := Decode(root, fileContents); err != nil { t.Fatal(err) }
+	Locate(root, fileContents)
+
+	// a body is read next to the file it is written in, the template's one
+	// for a body the step inherits
+	dir := func(name string) string { absolute, _ := filepath.Abs(name); return absolute }
+	located := map[string][2]string{
+		"beforeAll": {fileContents.BeforeAll[0].Dir, dir(".")},
+		"inherited body": {fileContents.PipelineBody[0].Dir, dir("templates")},
+		"own body": {fileContents.PipelineBody[1].Dir, dir(".")},
+		"included pipeline": {fileContents.CustomPipelines["users"].Steps[0].Dir, dir("shared")},
+	}
+	for name, dirs := range located {
+		if dirs[0] != dirs[1] { t.Errorf("%s located in %q, want %q", name, dirs[0], dirs[1]) }
+	}
+}
+
+func TestLocateData(t *testing.T) {
+	inDirectory(t, map[string]string{
+		"api.yaml": header + "include: pipelines/users.yaml\n",
+		"pipelines/users.yaml": "custom_pipelines:\n  users:\n    data: rows.csv\n    steps:\n      - endpoint: /users/{{name}}\n",
+		"pipelines/rows.csv": "name\nann\n",
+	})
+
+	// the validator reads the columns where the run reads the rows
+	root, err := Load("api.yaml", ParseYAML)
+	if err != nil { t.Fatal(err) }
+	if err := Validate(root); err != nil { t.Fatalf("Validate() did not find the data file next to its pipeline: %v", err) }
+
+	fileContents := &cmd.Structure{}
+	if err := Decode(root, fileContents); err != nil { t.Fatal(err) }
+	Locate(root, fileContents)
+
+	want, _ := filepath.Abs("pipelines")
+	if got := fileContents.CustomPipelines["users"].Dir; got != want { t.Errorf("data file located in %q, want %q", got, want) }
+}
+
+func TestLocateTLS(t *testing.T) {
+	inDirectory(t, map[string]string{
+		"api.yaml": header + "include: environments/tls.yaml\n",
+		"environments/tls.yaml": "environments:\n  development:\n    tls: { ca: ca.pem }\n  production:\n    tls: { ca: missing.pem }\n",
+		"environments/ca.pem": "not a certificate",
+	})
+
+	// the bundles are looked up next to the included file declaring them
+	root, err := Load("api.yaml", ParseYAML)
+	if err != nil { t.Fatal(err) }
+	err = Validate(root)
+	want := "environments/tls.yaml:3:10: environments.development.tls: Could not find any PEM certificate in CA bundle ca.pem\n" +
+		"environments/tls.yaml:5:10: environments.production.tls: Could not read CA bundle missing.pem"
+	if err == nil || err.Error() != want { t.Errorf("Validate() returned\n%v\nwant\n%s", err, want) }
+
+	fileContents := &cmd.Structure{}
+	if err := Decode(root, fileContents); err != nil { t.Fatal(err) }
+	Locate(root, fileContents)
+
+	dir, _ := filepath.Abs("environments")
+	for env, settings := range map[string]cmd.Settings{"development": fileContents.Environments.Development, "production": fileContents.Environments.Production, "staging": fileContents.Environments.Staging} {
+		want := dir
+		if env == "staging" { want = "" }
+		if settings.TLS.Dir != want { t.Errorf("the TLS settings of %s located in %q, want %q", env, settings.TLS.Dir, want) }
+	}
+	if !fileContents.Environments.Staging.TLS.Empty() { t.Errorf("staging has TLS settings it does not declare") }
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kind is the type of value held by a node
+type Kind int
+
+// the kinds of values a configuration file can hold
+const (
+	Null Kind = iota
+	Bool
+	Number
+	String
+	Array
+	Object
+)
+
+// String names the kind the way error messages show it
+func (k Kind) String() string {
+	return [...]string{"null", "boolean", "number", "string", "array", "object"}[k]
+}
+
+// Node is a value of a configuration file along with where it was written,
+// it allows errors to point at the exact file, line and column
+type Node struct {
+	Kind Kind
+	Value any
+	Keys []*Node
+	Fields map[string]*Node
+	Items []*Node
+	File string
+	Line int
+	Column int
+}
+
+// Position formats the location of the node as file:line:column
+func (n *Node) Position() string {
+	return fmt.Sprintf("%s:%d:%d", n.File, n.Line, n.Column)
+}
+
+// Field returns the value of an object key, nil when absent
+func (n *Node) Field(key string) *Node {
+	if n == nil || n.Kind != Object { return nil }
+	return n.Fields[key]
+}
+
+// Key returns the node of an object key itself, which points at the key
+// rather than at its value
+func (n *Node) Key(key string) *Node {
+	if n == nil { return nil }
+	for _, k := range n.Keys {
+		if k.Value == key { return k }
+	}
+	return nil
+}
+
+// set adds or replaces a key of an object node
+func (n *Node) set(key *Node, value *Node) {
+	if n.Fields == nil { n.Fields = map[string]*Node{} }
+	if _, exists := n.Fields[key.Value.(string)]; !exists { n.Keys = append(n.Keys, key)
+	} else {
+		for i := range n.Keys {
+			if n.Keys[i].Value == key.Value { n.Keys[i] = key }
+		}
+	}
+	n.Fields[key.Value.(string)] = value
+}
+
+// Interface converts the node into plain maps, slices and scalars
+func (n *Node) Interface() any {
+	switch n.Kind {
+	case Object:
+		out := make(map[string]any, len(n.Fields))
+		for key, value := range n.Fields { out[key] = value.Interface() }
+		return out
+	case Array:
+		out := make([]any, len(n.Items))
+		for i, item := range n.Items { out[i] = item.Interface() }
+		return out
+	}
+	return n.Value
+}
+
+// DisplayName shortens a path to be relative to the working directory when
+// possible, keeping error messages readable
+func DisplayName(path string) string {
+	wd, err := os.Getwd()
+	if err != nil { return path }
+
+	relative, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(relative, "..") { return path }
+	return relative
+}
+
+// Decode stores the node into a go value through its json tags
+func Decode(n *Node, target any) error {
+	data, err := json.Marshal(n.Interface())
+	if err != nil { return err }
+
+	return json.Unmarshal(data, target)
+}
+
+// Error is a problem found in a configuration file at a given location
+type Error struct {
+	File string
+	Line int
+	Column int
+	Message string
+}
+
+// Error formats the problem as file:line:column: message
+func (e *Error) Error() string {
+	if e.Line == 0 { return fmt.Sprintf("%s: %s", e.File, e.Message) }
+	if e.Column == 0 { return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message) }
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// errorAt creates an error pointing at a node
+func errorAt(n *Node, format string, args ...any) *Error {
+	return &Error{File: n.File, Line: n.Line, Column: n.Column, Message: fmt.Sprintf(format, args...)}
+}
+
+// Errors are all the problems found in a configuration file
+type Errors []*Error
+
+// Error lists every problem on its own line
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i := range e { lines[i] = e[i].Error() }
+	return strings.Join(lines, "\n")
+}
+
+// sorted orders the problems by their location
+func (e Errors) sorted() Errors {
+	sort.SliceStable(e, func(i, j int) bool {
+		if e[i].File != e[j].File { return e[i].File < e[j].File }
+		if e[i].Line != e[j].Line { return e[i].Line < e[j].Line }
+		if e[i].Column != e[j].Column { return e[i].Column < e[j].Column }
+		return e[i].Message < e[j].Message
+	})
+	return e
+}
+
+// jsonMarshal is json.Marshal without the html escaping
+func jsonMarshal(value any) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil { return nil, err }
+
+	return bytes.TrimRight(buffer.Bytes(), "\n"), nil
+}
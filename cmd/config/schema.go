@@ -0,0 +1,147 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// Schema is the subset of JSON Schema (draft 2020-12) describing the
+// configuration file. It is generated from the go types the file is decoded
+// into, and the validator walks the very same schema
+type Schema struct {
+	Schema string `json:"$schema,omitempty"`
+	Title string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Ref string `json:"$ref,omitempty"`
+	Type string `json:"type,omitempty"`
+	Enum []any `json:"enum,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	AnyOf []*Schema `json:"anyOf,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required []string `json:"required,omitempty"`
+	AdditionalProperties *Schema `json:"additionalProperties,omitempty"`
+	Closed bool `json:"-"`
+	Items *Schema `json:"items,omitempty"`
+	Defs map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// MarshalJSON writes `additionalProperties: false` for closed objects
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	if !s.Closed { return jsonMarshal((*plain)(s)) }
+
+	return jsonMarshal(struct {
+		*plain
+		AdditionalProperties bool `json:"additionalProperties"`
+	}{(*plain)(s), false})
+}
+
+// durationPattern matches go durations such as 300ms, 5s or 1m30s
+const durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+var (
+	generated *Schema
+	generateOnce sync.Once
+)
+
+// GenerateSchema returns the JSON Schema of the configuration file
+func GenerateSchema() *Schema {
+	generateOnce.Do(func() {
+		defs := map[string]*Schema{}
+		generated = schemaOf(reflect.TypeOf(cmd.Structure{}), defs, true)
+		generated.Schema = "https://json-schema.org/draft/2020-12/schema"
+		generated.Title = "apee-i configuration"
+		generated.Defs = defs
+	})
+	return generated
+}
+
+// resolve follows a `#/$defs/...` reference
+func (s *Schema) resolve() *Schema {
+	if s.Ref == "" { return s }
+	return GenerateSchema().Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+}
+
+// schemaOf describes a go type, named structs are stored once in defs and referenced
+func schemaOf(t reflect.Type, defs map[string]*Schema, root bool) *Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem(), defs, root)
+
+	case reflect.Struct:
+		if !root {
+			if _, exists := defs[t.Name()]; !exists {
+				defs[t.Name()] = nil
+				defs[t.Name()] = schemaOf(t, defs, true)
+			}
+			reference := &Schema{Ref: "#/$defs/" + t.Name()}
+
+			// pipelines are written as a bare list of steps as well
+			if t == reflect.TypeOf(cmd.Pipeline{}) {
+				return &Schema{AnyOf: []*Schema{schemaOf(reflect.TypeOf([]cmd.PipelineBody{}), defs, false), reference}}
+			}
+			return reference
+		}
+
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}, Closed: true}
+		for name, field := range jsonFields(t) {
+			property := schemaOf(field.Type, defs, false)
+			if description := field.Tag.Get("description"); description != "" {
+				if property.Ref != "" { property = &Schema{Ref: property.Ref} }
+				property.Description = description
+			}
+			if enum := field.Tag.Get("enum"); enum != "" {
+				for _, value := range strings.Split(enum, ",") { property.Enum = append(property.Enum, value) }
+			}
+			if field.Tag.Get("format") == "duration" { property.Pattern = durationPattern }
+			if field.Tag.Get("required") == "true" { s.Required = append(s.Required, name) }
+			s.Properties[name] = property
+		}
+		sort.Strings(s.Required)
+		return s
+
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), defs, false)}
+
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), defs, false)}
+
+	case reflect.String:
+		return &Schema{Type: "string"}
+
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return &Schema{Type: "integer"}
+
+	case reflect.Float64, reflect.Float32:
+		return &Schema{Type: "number"}
+	}
+
+	// interfaces accept any value
+	return &Schema{}
+}
+
+// jsonFields maps the json names of a struct to its fields
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		// embedded structs add their fields the way encoding/json does
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for embedded, inner := range jsonFields(field.Type) { fields[embedded] = inner }
+			continue
+		}
+		if name == "-" || !field.IsExported() { continue }
+		if name == "" { name = field.Name }
+		fields[name] = field
+	}
+	return fields
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// schemaJSON is the schema as `apee-i schema` writes it, read back as plain values
+func schemaJSON(t *testing.T) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(GenerateSchema())
+	if err != nil { t.Fatal(err) }
+
+	schema := map[string]any{}
+	if err := json.Unmarshal(data, &schema); err != nil { t.Fatal(err) }
+	return schema
+}
+
+// at follows a path of keys through the schema
+func at(t *testing.T, value any, keys ...string) any {
+	t.Helper()
+	for _, key := range keys {
+		object, ok := value.(map[string]any)
+		if !ok { t.Fatalf("schema has no %s", strings.Join(keys, ".")) }
+		value = object[key]
+	}
+	return value
+}
+
+func TestGenerateSchema(t *testing.T) {
+	schema := schemaJSON(t)
+
+	if at(t, schema, "$schema") != "https://json-schema.org/draft/2020-12/schema" { t.Errorf("schema is not a draft 2020-12 schema") }
+	if at(t, schema, "additionalProperties") != false { t.Errorf("the root accepts unknown keys") }
+
+	// the run time state of the structure is never written in a file
+	properties := at(t, schema, "properties").(map[string]any)
+	for _, hidden := range []string{"ActiveURL", "ConfigDir", "Transport", "Results", "Jar"} {
+		if _, exists := properties[hidden]; exists { t.Errorf("schema lists %s", hidden) }
+	}
+	for _, key := range []string{"baseUrl", "current_pipeline", "custom_pipelines", "beforeAll", "afterEach", "environments"} {
+		if _, exists := properties[key]; !exists { t.Errorf("schema misses the root key %s", key) }
+	}
+
+	// steps are described once and referenced
+	if ref := at(t, schema, "properties", "current_pipeline", "items", "$ref"); ref != "#/$defs/PipelineBody" { t.Errorf("current_pipeline items are %v", ref) }
+	if enum := at(t, schema, "$defs", "PipelineBody", "properties", "bodyType", "enum"); !reflect.DeepEqual(enum, []any{"json", "form", "multipart", "raw", "binary", "none"}) { t.Errorf("bodyType enum is %v", enum) }
+	if pattern := at(t, schema, "$defs", "PipelineBody", "properties", "timeout", "pattern"); pattern != durationPattern { t.Errorf("timeout pattern is %v", pattern) }
+	if required := at(t, schema, "$defs", "GRPC", "required"); !reflect.DeepEqual(required, []any{"method", "service"}) { t.Errorf("grpc requires %v", required) }
+	if kind := at(t, schema, "$defs", "PipelineBody", "properties", "expectedStatusCode", "type"); kind != "integer" { t.Errorf("expectedStatusCode is of type %v", kind) }
+
+	// a custom pipeline is either a bare list of steps or an object
+	alternatives := at(t, schema, "properties", "custom_pipelines", "additionalProperties", "anyOf").([]any)
+	if len(alternatives) != 2 || at(t, alternatives[0], "type") != "array" || at(t, alternatives[1], "$ref") != "#/$defs/Pipeline" {
+		t.Errorf("custom pipelines are %v", alternatives)
+	}
+}
+
+func TestValidateAgainstSchema(t *testing.T) {
+	config := header + `current_pipeline:
+  - endpoint: /a
+    timeout: 5 seconds
+    expectedStatusCode: 200.5
+    tags: smoke
+    followRedirects: null
+custom_pipelines:
+  users: /users
+  orders:
+    steps:
+      - endpoint: /orders
+        grpc: { service: orders.Orders }
+`
+	root, err := ParseYAML("api.yaml", []byte(config))
+	if err != nil { t.Fatal(err) }
+
+	err = Validate(root)
+	if err == nil { t.Fatal("Validate() accepted the configuration") }
+
+	// every problem is reported at once, in the order of the file
+	want := []string{
+		`api.yaml:7:14: current_pipeline[0].timeout: expected a duration such as 500ms or 5s, got string "5 seconds"`,
+		`api.yaml:8:25: current_pipeline[0].expectedStatusCode: expected integer, got number 200.5`,
+		`api.yaml:9:11: current_pipeline[0].tags: expected array, got string "smoke"`,
+		`api.yaml:12:10: custom_pipelines.users: expected array or object, got string "/users"`,
+		`api.yaml:16:15: custom_pipelines.orders.steps[0].grpc: missing required key "method"`,
+	}
+	if got := strings.Split(err.Error(), "\n"); !reflect.DeepEqual(got, want) { t.Errorf("Validate() returned\n%s\nwant\n%s", err, strings.Join(want, "\n")) }
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"strings"
+)
+
+// templateResolver expands templates once, remembering the chain being
+// expanded to reject cycles
+type templateResolver struct {
+	v *validator
+	templates *Node
+	expanded map[string]*Node
+	chain []string
+}
+
+// templates expands every step that extends a template. Templates can extend
+// other templates, objects such as body and headers are deep merged and any
+// other value set on the step wins over the template
+func (v *validator) templates(root *Node) {
+	r := &templateResolver{v: v, templates: root.Field("templates"), expanded: map[string]*Node{}}
+
+	if r.templates != nil {
+		for _, key := range r.templates.Keys { r.template(key.Value.(string), key) }
+	}
+
+	for _, pipeline := range pipelines(root) {
+		for i, step := range pipeline.steps.Items {
+			if expanded := r.step(step); expanded != nil { pipeline.steps.Items[i] = expanded }
+		}
+	}
+}
+
+// step returns the step merged over its template, nil when it extends nothing or fails
+func (r *templateResolver) step(step *Node) *Node {
+	extends := step.Field("extends")
+	if extends == nil || extends.Kind != String { return nil }
+
+	template := r.template(extends.Value.(string), extends)
+	if template == nil { return nil }
+
+	merged, _ := merge(template, step, true, "step")
+	return withoutKey(merged, "extends")
+}
+
+// template returns a fully expanded template by name
+func (r *templateResolver) template(name string, reference *Node) *Node {
+	if expanded, done := r.expanded[name]; done { return expanded }
+
+	for i, parent := range r.chain {
+		if parent == name {
+			r.v.add(reference, "extends cycle: %s", strings.Join(append(r.chain[i:], name), " -> "))
+			return nil
+		}
+	}
+
+	template := r.templates.Field(name)
+	if template == nil { r.v.add(reference, "extends unknown template %q", name); return nil }
+
+	r.chain = append(r.chain, name)
+	defer func() { r.chain = r.chain[:len(r.chain)-1] }()
+
+	expanded := template
+	if extends := template.Field("extends"); extends != nil && extends.Kind == String {
+		parent := r.template(extends.Value.(string), extends)
+		if parent == nil { r.expanded[name] = nil; return nil }
+		expanded, _ = merge(parent, template, true, "step")
+		expanded = withoutKey(expanded, "extends")
+	}
+
+	r.expanded[name] = expanded
+	return expanded
+}
+
+// stepList is an array of steps along with where it sits in the file
+type stepList struct {
+	path string
+	steps *Node
+}
+
+// hookNames are the keys holding the steps of hooks, at the root and on
+// custom pipelines
+var hookNames = []string{"beforeAll", "afterAll", "beforeEach", "afterEach"}
+
+// pipelines lists the step arrays of the current and the custom pipelines
+// along with the ones of their hooks
+func pipelines(root *Node) []stepList {
+	list := []stepList{}
+	add := func(owner *Node, path string) {
+		for _, hook := range hookNames {
+			if steps := owner.Field(hook); steps != nil { list = append(list, stepList{join(path, hook), steps}) }
+		}
+	}
+
+	if current := root.Field("current_pipeline"); current != nil { list = append(list, stepList{"current_pipeline", current}) }
+	add(root, "")
+	if custom := root.Field("custom_pipelines"); custom != nil {
+		for _, key := range custom.Keys {
+			path, pipeline := join("custom_pipelines", key.Value.(string)), custom.Fields[key.Value.(string)]
+			if pipeline.Kind != Object { list = append(list, stepList{path, pipeline}); continue }
+			if steps := pipeline.Field("steps"); steps != nil { list = append(list, stepList{join(path, "steps"), steps}) }
+			add(pipeline, path)
+		}
+	}
+	return list
+}
+
+// withoutKey copies an object node leaving one key out
+func withoutKey(n *Node, key string) *Node {
+	if n.Field(key) == nil { return n }
+
+	out := &Node{Kind: Object, Fields: map[string]*Node{}, File: n.File, Line: n.Line, Column: n.Column}
+	for _, k := range n.Keys {
+		if k.Value != key { out.set(k, n.Fields[k.Value.(string)]) }
+	}
+	return out
+}
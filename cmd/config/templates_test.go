@@ -0,0 +1,59 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTemplatesExtend(t *testing.T) {
+	config := header + `variables: { token: abc }
+templates:
+  authed:
+    headers: { Authorization: "{{token}}" }
+    expectedStatusCode: 200
+  admin:
+    extends: authed
+    headers: { X-Role: admin }
+    method: POST
+current_pipeline:
+  - extends: admin
+    endpoint: /users
+    expectedStatusCode: 201
+`
+	root, err := ParseYAML("api.yaml", []byte(config))
+	if err != nil { t.Fatal(err) }
+	if err := Validate(root); err != nil { t.Fatalf("Validate() returned %v", err) }
+
+	// headers are merged along the chain and the step has the last word
+	step := root.Field("current_pipeline").Items[0].Interface()
+	want := map[string]any{
+		"headers": map[string]any{"Authorization": "{{token}}", "X-Role": "admin"},
+		"method": "POST",
+		"endpoint": "/users",
+		"expectedStatusCode": float64(201),
+	}
+	if !reflect.DeepEqual(step, want) { t.Errorf("the expanded step is %v, want %v", step, want) }
+}
+
+func TestTemplatesCycle(t *testing.T) {
+	cycles := []struct {
+		templates string
+		step string
+		want string
+	}{
+		{"  self:\n    extends: self\n", "self", "api.yaml:7:14: extends cycle: self -> self"},
+		{"  a:\n    extends: b\n  b:\n    extends: a\n", "b", "api.yaml:9:14: extends cycle: a -> b -> a"},
+		{"  a:\n    extends: b\n  b:\n    extends: c\n  c:\n    extends: b\n", "a", "api.yaml:11:14: extends cycle: b -> c -> b"},
+	}
+
+	for _, cycle := range cycles {
+		config := header + "templates:\n" + cycle.templates + "current_pipeline:\n  - extends: " + cycle.step + "\n    endpoint: /a\n"
+		root, err := ParseYAML("api.yaml", []byte(config))
+		if err != nil { t.Fatal(err) }
+
+		// a cycle is reported once however many steps and templates reach it
+		err = Validate(root)
+		if err == nil { t.Errorf("Validate() accepted the templates\n%s", cycle.templates); continue }
+		if err.Error() != cycle.want { t.Errorf("Validate() returned\n%s\nwant %s", err, cycle.want) }
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// tomlPositions remembers where every key was found, in document order.
+// The toml decoder does not expose key positions so they are searched for
+// line by line, following the order of the keys in the document
+type tomlPositions struct {
+	lines []string
+	cursor int
+	order map[string]int
+	found map[string][][2]int
+}
+
+// ParseTOML parses a toml document into a tree of nodes
+func ParseTOML(file string, data []byte) (*Node, error) {
+	document := map[string]any{}
+	metadata, err := toml.Decode(string(data), &document)
+	if err != nil {
+		if parseError, ok := err.(toml.ParseError); ok {
+			return nil, &Error{File: file, Line: parseError.Position.Line, Message: tomlMessage(parseError)}
+		}
+		return nil, &Error{File: file, Message: err.Error()}
+	}
+
+	positions := &tomlPositions{lines: strings.Split(string(data), "\n"), order: map[string]int{}, found: map[string][][2]int{}}
+	for i, key := range metadata.Keys() {
+		// the tables implied by `[a.b]` headers are not listed on their own
+		for depth := 1; depth <= len(key); depth++ {
+			path := strings.Join(key[:depth], ".")
+			_, seen := positions.order[path]
+			if seen && depth < len(key) { continue }
+			if !seen { positions.order[path] = i }
+			positions.found[path] = append(positions.found[path], positions.search(key[depth-1]))
+		}
+	}
+
+	root := &Node{Kind: Object, Fields: map[string]*Node{}, File: file, Line: 1, Column: 1}
+	positions.object(file, root, document, "")
+	return root, nil
+}
+
+// tomlMessage is the message of a parse error without the line the error
+// already carries, some errors of the decoder only have it in Error()
+func tomlMessage(parseError toml.ParseError) string {
+	if parseError.Message != "" { return parseError.Message }
+
+	prefix := fmt.Sprintf("toml: line %d: ", parseError.Position.Line)
+	if parseError.LastKey != "" { prefix = fmt.Sprintf("toml: line %d (last key %q): ", parseError.Position.Line, parseError.LastKey) }
+	return strings.TrimPrefix(parseError.Error(), prefix)
+}
+
+// search finds the line and column of a key, starting at the line of the previous key
+func (p *tomlPositions) search(key string) [2]int {
+	pattern := regexp.MustCompile(`(^|[\s.{,\[])["']?(` + regexp.QuoteMeta(key) + `)["']?\s*(=|\]|\.)`)
+	for line := p.cursor; line < len(p.lines); line++ {
+		if match := pattern.FindStringSubmatchIndex(p.lines[line]); match != nil {
+			p.cursor = line
+			return [2]int{line + 1, match[4] + 1}
+		}
+	}
+	return [2]int{}
+}
+
+// take hands out the next position recorded for a key path
+func (p *tomlPositions) take(path string) [2]int {
+	queue := p.found[path]
+	if len(queue) == 0 { return [2]int{} }
+
+	p.found[path] = queue[1:]
+	return queue[0]
+}
+
+// object fills an object node keeping the keys in document order
+func (p *tomlPositions) object(file string, n *Node, values map[string]any, path string) {
+	keys := make([]string, 0, len(values))
+	for key := range values { keys = append(keys, key) }
+	sort.Slice(keys, func(i, j int) bool { return p.order[join(path, keys[i])] < p.order[join(path, keys[j])] })
+
+	for _, key := range keys {
+		position := p.take(join(path, key))
+		keyNode := &Node{Kind: String, Value: key, File: file, Line: position[0], Column: position[1]}
+		n.set(keyNode, p.value(file, values[key], join(path, key), position))
+	}
+}
+
+// value converts a decoded toml value into a node
+func (p *tomlPositions) value(file string, value any, path string, position [2]int) *Node {
+	n := &Node{File: file, Line: position[0], Column: position[1]}
+
+	switch v := value.(type) {
+	case map[string]any:
+		n.Kind, n.Fields = Object, map[string]*Node{}
+		p.object(file, n, v, path)
+	case []map[string]any:
+		// every [[table]] header after the first one is a position of its own
+		n.Kind, n.Items = Array, []*Node{}
+		for i, item := range v {
+			if i > 0 { position = p.take(path) }
+			n.Items = append(n.Items, p.value(file, item, path, position))
+		}
+	case []any:
+		n.Kind, n.Items = Array, []*Node{}
+		for _, item := range v { n.Items = append(n.Items, p.value(file, item, path, position)) }
+	case bool: n.Kind, n.Value = Bool, v
+	case int64: n.Kind, n.Value = Number, float64(v)
+	case float64: n.Kind, n.Value = Number, v
+	case string: n.Kind, n.Value = String, v
+	case time.Time: n.Kind, n.Value = String, v.Format(time.RFC3339Nano)
+	default: n.Kind, n.Value = String, fmt.Sprint(v)
+	}
+
+	return n
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+const tomlConfig = `baseUrl = { development = "http://localhost" }
+
+[[current_pipeline]]
+endpoint = "/users"
+expectedStatusCode = 201
+
+[[current_pipeline]]
+endpoint = "/orders"
+after = 2024-01-02T03:04:05Z
+
+[custom_pipelines.users]
+data = "users.csv"
+`
+
+func TestParseTOML(t *testing.T) {
+	root, err := ParseTOML("api.toml", []byte(tomlConfig))
+	if err != nil { t.Fatalf("ParseTOML() returned %v", err) }
+
+	// integers read as the numbers json gives and dates as strings
+	want := map[string]any{
+		"baseUrl": map[string]any{"development": "http://localhost"},
+		"current_pipeline": []any{
+			map[string]any{"endpoint": "/users", "expectedStatusCode": float64(201)},
+			map[string]any{"endpoint": "/orders", "after": "2024-01-02T03:04:05Z"},
+		},
+		"custom_pipelines": map[string]any{"users": map[string]any{"data": "users.csv"}},
+	}
+	if got := root.Interface(); !reflect.DeepEqual(got, want) { t.Errorf("ParseTOML() = %v, want %v", got, want) }
+
+	// the keys come in document order with the line and column they are at
+	keys := []string{}
+	for _, key := range root.Keys { keys = append(keys, key.Value.(string)) }
+	if want := []string{"baseUrl", "current_pipeline", "custom_pipelines"}; !reflect.DeepEqual(keys, want) { t.Errorf("keys are %v, want %v", keys, want) }
+
+	steps := root.Field("current_pipeline").Items
+	positions := map[string]*Node{
+		"3:3": steps[0],
+		"7:3": steps[1],
+		"8:1": steps[1].Key("endpoint"),
+		"11:2": root.Key("custom_pipelines"),
+		"12:1": root.Field("custom_pipelines").Field("users").Key("data"),
+	}
+	for want, n := range positions {
+		if got := n.Position(); got != "api.toml:" + want { t.Errorf("node at %s, want api.toml:%s", got, want) }
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for data, want := range map[string]string{
+		"a = 1\nb = [\n": "api.toml:2: unexpected EOF; expected value",
+		"a = 1\na = 2\n": "api.toml:2: Key 'a' has already been defined.",
+		"[table\n": "api.toml:2: expected '.' or ']' to end table name, but got '\\n' instead",
+	} {
+		_, err := ParseTOML("api.toml", []byte(data))
+		if err == nil || err.Error() != want { t.Errorf("ParseTOML(%q) returned %v, want %s", data, err, want) }
+	}
+}
@@ -0,0 +1,332 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/expr"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/script"
+)
+
+// validator collects every problem found while walking a configuration tree
+type validator struct {
+	errors Errors
+}
+
+// Validate checks a configuration tree against the JSON Schema generated
+// from cmd.Structure, reporting unknown keys, wrong types and missing required
+// keys. Steps extending templates are then expanded in place, and the expanded
+// steps are checked for their endpoint, for a body fitting their body type,
+// for conditions that do not parse, for variables that are never defined
+// or captured, for certificates that cannot be loaded and for custom
+// pipelines named like the ones --pipeline selects
+func Validate(root *Node) error {
+	v := &validator{}
+	v.check(root, GenerateSchema(), "")
+	if len(v.errors) == 0 { v.templates(root) }
+	if len(v.errors) == 0 { v.endpoints(root); v.bodies(root); v.conditions(root); v.variables(root); v.certificates(root); v.reserved(root) }
+
+	if len(v.errors) == 0 { return nil }
+	return v.errors.sorted()
+}
+
+func (v *validator) add(n *Node, format string, args ...any) {
+	v.errors = append(v.errors, errorAt(n, format, args...))
+}
+
+// check walks a node along with the schema it has to satisfy
+func (v *validator) check(n *Node, schema *Schema, path string) {
+	schema = schema.resolve()
+	if n.Kind == Null { return }
+
+	// the first alternative of the right type is the one checked
+	if len(schema.AnyOf) > 0 {
+		types := []string{}
+		for _, alternative := range schema.AnyOf {
+			if matchesType(n, alternative.resolve().Type) { v.check(n, alternative, path); return }
+			types = append(types, alternative.resolve().Type)
+		}
+		v.add(n, "%s: expected %s, got %s", label(path), strings.Join(types, " or "), describe(n)); return
+	}
+	if schema.Type == "" { return }
+
+	if !matchesType(n, schema.Type) { v.add(n, "%s: expected %s, got %s", label(path), schema.Type, describe(n)); return }
+
+	if len(schema.Enum) > 0 && !inEnum(n.Value, schema.Enum) {
+		v.add(n, "%s: expected one of %s, got %s", label(path), enumList(schema.Enum), describe(n))
+	}
+
+	if schema.Pattern != "" && n.Kind == String && !regexp.MustCompile(schema.Pattern).MatchString(n.Value.(string)) {
+		if schema.Pattern == durationPattern { v.add(n, "%s: expected a duration such as 500ms or 5s, got %s", label(path), describe(n)); return }
+		v.add(n, "%s: expected a value matching %s, got %s", label(path), schema.Pattern, describe(n))
+	}
+
+	switch n.Kind {
+	case Object:
+		for _, key := range n.Keys {
+			name := key.Value.(string)
+			property := schema.Properties[name]
+			if property == nil { property = schema.AdditionalProperties }
+			if property == nil {
+				if schema.Closed { v.add(key, "unknown key %q in %s", name, label(path)) }
+				continue
+			}
+			v.check(n.Fields[name], property, join(path, name))
+		}
+		for _, name := range schema.Required {
+			if n.Field(name) == nil { v.add(n, "%s: missing required key %q", label(path), name) }
+		}
+
+	case Array:
+		if schema.Items == nil { return }
+		for i, item := range n.Items {
+			v.check(item, schema.Items, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// matchesType tells if a node is of the given JSON Schema type
+func matchesType(n *Node, schemaType string) bool {
+	switch schemaType {
+	case "object": return n.Kind == Object
+	case "array": return n.Kind == Array
+	case "string": return n.Kind == String
+	case "boolean": return n.Kind == Bool
+	case "number": return n.Kind == Number
+	case "integer": return n.Kind == Number && n.Value.(float64) == math.Trunc(n.Value.(float64))
+	}
+	return true
+}
+
+func inEnum(value any, enum []any) bool {
+	for _, allowed := range enum {
+		if allowed == value { return true }
+	}
+	return false
+}
+
+func enumList(enum []any) string {
+	values := make([]string, len(enum))
+	for i := range enum { values[i] = fmt.Sprint(enum[i]) }
+	return strings.Join(values, ", ")
+}
+
+// endpoints reports the steps left without an endpoint once templates are
+// expanded, gRPC steps need their call instead. The protocol specific blocks
+// are checked against the kind of the step as well
+func (v *validator) endpoints(root *Node) {
+	check := func(pipeline *Node, path string) {
+		for i, step := range pipeline.Items {
+			kind := step.Field("kind")
+			if kind != nil && kind.Value == "grpc" {
+				if step.Field("grpc") == nil { v.add(kind, "%s[%d]: kind \"grpc\" needs a grpc call", path, i) }
+				continue
+			}
+			if grpc := step.Field("grpc"); grpc != nil { v.add(grpc, "%s[%d]: grpc is only used by steps of kind \"grpc\"", path, i) }
+			if stream := step.Field("stream"); stream != nil {
+				if kind == nil || (kind.Value != "websocket" && kind.Value != "sse") {
+					v.add(stream, "%s[%d]: stream is only used by steps of kind \"websocket\" or \"sse\"", path, i)
+				} else if send := stream.Field("send"); send != nil && kind.Value == "sse" {
+					v.add(send, "%s[%d]: server-sent events cannot send messages", path, i)
+				}
+			}
+			if step.Field("endpoint") == nil { v.add(step, "%s[%d]: missing required key \"endpoint\"", path, i) }
+		}
+	}
+
+	for _, pipeline := range pipelines(root) { check(pipeline.steps, pipeline.path) }
+}
+
+// bodies reports the steps whose body cannot be encoded with their body type
+func (v *validator) bodies(root *Node) {
+	for _, step := range steps(root) {
+		bodyType, body := step.Field("bodyType"), step.Field("body")
+		if graphql := step.Field("graphql"); graphql != nil {
+			if body != nil { v.add(body, "body cannot be set along with graphql, the operation is the body") }
+			if bodyType != nil && bodyType.Value != "json" { v.add(bodyType, "bodyType %q cannot be used with graphql, operations are sent as json", bodyType.Value) }
+			continue
+		}
+		if bodyType == nil || body == nil || body.Kind == Null { continue }
+
+		switch bodyType.Value {
+		case "none":
+			v.add(body, "body is set but bodyType is \"none\", it would not be sent")
+		case "form", "multipart":
+			if body.Kind != Object { v.add(body, "bodyType %q needs an object body, got %s", bodyType.Value, describe(body)) }
+		case "raw":
+			if body.Kind != String && !isFileReference(body) { v.add(body, "bodyType \"raw\" needs a string or {file: path} body, got %s", describe(body)) }
+		case "binary":
+			if !isFileReference(body) { v.add(body, "bodyType \"binary\" needs a {file: path} body, got %s", describe(body)) }
+		}
+	}
+}
+
+// conditions reports the expressions and the scripts of the steps that do not parse
+func (v *validator) conditions(root *Node) {
+	check := func(n *Node, path string) {
+		if n == nil || n.Kind != String { return }
+		if _, err := expr.Parse(n.Value.(string)); err != nil { v.add(n, "%s: %v", path, err) }
+	}
+
+	for _, step := range steps(root) {
+		check(step.Field("if"), "if")
+		check(step.Field("forEach"), "forEach")
+		check(step.Field("waitUntil").Field("condition"), "waitUntil.condition")
+		if assert := step.Field("assert"); assert != nil {
+			for _, assertion := range assert.Items { check(assertion, "assert") }
+		}
+
+		for _, name := range []string{"preRequest", "postResponse"} {
+			if source := step.Field(name); source != nil && source.Kind == String {
+				if err := script.Check(name, source.Value.(string)); err != nil { v.add(source, "%v", err) }
+			}
+		}
+	}
+}
+
+// certificates reports the TLS settings of the environments whose files
+// cannot be loaded, the files being read relative to the file declaring them
+func (v *validator) certificates(root *Node) {
+	for _, env := range cmd.EnvironmentNames {
+		settings := root.Field("environments").Field(env).Field("tls")
+		if settings == nil || settings.Kind != Object { continue }
+
+		text := func(key string) string {
+			if n := settings.Field(key); n != nil && n.Kind == String { return n.Value.(string) }
+			return ""
+		}
+		config := cmd.TLS{CA: text("ca"), Cert: text("cert"), Key: text("key"), MinVersion: text("minVersion")}
+		if _, err := config.Config(settings.Dir()); err != nil { v.add(settings, "environments.%s.tls: %v", env, err) }
+	}
+}
+
+// reserved reports the custom pipelines named current or all, which could
+// not be told apart from the current pipeline and from every pipeline
+func (v *validator) reserved(root *Node) {
+	custom := root.Field("custom_pipelines")
+	if custom == nil { return }
+
+	for _, key := range custom.Keys {
+		if key.Value == cmd.CurrentPipelineName || key.Value == "all" {
+			v.add(key, "custom_pipelines: %q is reserved, pick another name for this pipeline", key.Value)
+		}
+	}
+}
+
+// isFileReference tells if a node is a `{file: path}` object
+func isFileReference(n *Node) bool {
+	file := n.Field("file")
+	return n.Kind == Object && file != nil && file.Kind == String
+}
+
+// variables reports every `{{name}}` that is neither declared in `variables`,
+// captured by a step or stored by its script, a column of a data file nor
+// the item of a forEach, and
+// the generators called with a wrong number of arguments. Data files are read
+// relative to the file declaring them
+func (v *validator) variables(root *Node) {
+	known := map[string]bool{}
+	if declared := root.Field("variables"); declared != nil {
+		for name := range declared.Fields { known[name] = true }
+	}
+
+	if custom := root.Field("custom_pipelines"); custom != nil {
+		for _, pipeline := range custom.Fields {
+			data := pipeline.Field("data")
+			if data == nil || data.Kind != String { continue }
+
+			path := data.Value.(string)
+			if !filepath.IsAbs(path) { path = filepath.Join(data.Dir(), path) }
+			rows, err := cmd.ReadRows(path)
+			if err != nil { v.add(data, "%s", err.Error()); continue }
+			for _, row := range rows {
+				for name := range row { known[name] = true }
+			}
+		}
+	}
+
+	steps := steps(root)
+	for _, step := range steps {
+		if capture := step.Field("capture"); capture != nil {
+			for name := range capture.Fields { known[name] = true }
+		}
+		for _, name := range []string{"preRequest", "postResponse"} {
+			if source := step.Field(name); source != nil && source.Kind == String {
+				for _, variable := range cmd.ScriptAssignments(name, source.Value.(string)) { known[variable] = true }
+			}
+		}
+		if step.Field("forEach") != nil {
+			as := step.Field("as")
+			if as == nil { known["item"] = true } else { known[fmt.Sprint(as.Value)] = true }
+		}
+		if expect := step.Field("stream").Field("expect"); expect != nil {
+			for _, expectation := range expect.Items {
+				if capture := expectation.Field("capture"); capture != nil {
+					for name := range capture.Fields { known[name] = true }
+				}
+			}
+		}
+	}
+
+	report := func(n *Node) {
+		for _, name := range cmd.References(n.Value.(string)) {
+			if !known[name] { v.add(n, "undefined variable %q, it is neither declared in variables, captured or stored by a step, a column of a data file nor the item of a forEach", name) }
+		}
+		for name, counts := range cmd.Generators(n.Value.(string)) {
+			for _, count := range counts {
+				if err := cmd.CheckGenerator(name, count); err != nil { v.add(n, "%v", err) }
+			}
+		}
+	}
+
+	for _, step := range steps {
+		for _, key := range []string{"endpoint", "headers", "query", "cookies", "body"} { walkStrings(step.Field(key), report) }
+		walkStrings(step.Field("graphql").Field("variables"), report)
+		walkStrings(step.Field("grpc").Field("message"), report)
+		walkStrings(step.Field("stream"), report)
+	}
+
+	// defaults are interpolated before every request as well
+	walkStrings(root.Field("defaults"), report)
+	walkStrings(root.Field("environments"), report)
+}
+
+// steps lists the step nodes of the current and the custom pipelines and of their hooks
+func steps(root *Node) []*Node {
+	list := []*Node{}
+	for _, pipeline := range pipelines(root) { list = append(list, pipeline.steps.Items...) }
+	return list
+}
+
+// walkStrings calls fn for every string found under a node
+func walkStrings(n *Node, fn func(*Node)) {
+	if n == nil { return }
+	switch n.Kind {
+	case String: fn(n)
+	case Array: for _, item := range n.Items { walkStrings(item, fn) }
+	case Object: for _, item := range n.Fields { walkStrings(item, fn) }
+	}
+}
+
+// describe names the kind of a node, showing scalars along with their value
+func describe(n *Node) string {
+	switch n.Kind {
+	case String: return fmt.Sprintf("string %q", n.Value)
+	case Number, Bool: return fmt.Sprintf("%s %v", n.Kind, n.Value)
+	}
+	return n.Kind.String()
+}
+
+func join(path string, key string) string {
+	if path == "" { return key }
+	return path + "." + key
+}
+
+func label(path string) string {
+	if path == "" { return "root" }
+	return path
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// header is the part every configuration of the tests starts with
+const header = `baseUrl:
+  development: http://localhost
+loginDetails:
+  type: none
+`
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		config string
+		want []string
+	}{
+		{
+			name: "valid",
+			config: `variables: { id: 1 }
+current_pipeline:
+  - name: getUser
+    endpoint: /users/{{id}}
+    capture: { userName: name }
+  - endpoint: /greet/{{userName}}/{{uuid}}
+    method: POST
+    bodyType: form
+    body: { name: "{{randomName}}" }
+    assert: [status == 200]
+`,
+		},
+		{
+			name: "unknown key",
+			config: "current_pipeline:\n  - endpoint: /a\n    expected: 200\n",
+			want: []string{"api.yaml:7:5: unknown key \"expected\" in current_pipeline[0]"},
+		},
+		{
+			name: "wrong type",
+			config: "current_pipeline:\n  - endpoint: /a\n    expectedStatusCode: \"200\"\n",
+			want: []string{"api.yaml:7:25: current_pipeline[0].expectedStatusCode: expected integer, got string \"200\""},
+		},
+		{
+			name: "enum",
+			config: "current_pipeline:\n  - endpoint: /a\n    bodyType: xml\n",
+			want: []string{"api.yaml:7:15: current_pipeline[0].bodyType: expected one of json, form, multipart, raw, binary, none, got string \"xml\""},
+		},
+		{
+			name: "missing endpoint",
+			config: "current_pipeline:\n  - method: GET\n",
+			want: []string{"current_pipeline[0]: missing required key \"endpoint\""},
+		},
+		{
+			name: "body of bodyType none",
+			config: "current_pipeline:\n  - endpoint: /a\n    bodyType: none\n    body: { a: 1 }\n",
+			want: []string{"api.yaml:8:11: body is set but bodyType is \"none\", it would not be sent"},
+		},
+		{
+			name: "undefined variable",
+			config: "current_pipeline:\n  - endpoint: /users/{{id}}\n",
+			want: []string{"api.yaml:6:15: undefined variable \"id\""},
+		},
+		{
+			name: "variable stored by a script",
+			config: "current_pipeline:\n  - endpoint: /a\n    postResponse: |\n      vars[\"token\"] = \"abc\"\n  - endpoint: /b\n    headers: { X-Token: \"{{token}}\" }\n",
+		},
+		{
+			name: "variable used by cookies, expectedBody is not interpolated",
+			config: "current_pipeline:\n  - endpoint: /a\n    cookies: { sid: \"{{session}}\" }\n    expectedBody: { id: \"{{userId}}\" }\n",
+			want: []string{"undefined variable \"session\""},
+		},
+		{
+			name: "generator arguments",
+			config: "current_pipeline:\n  - endpoint: /a/{{randomInt 1}}\n",
+			want: []string{"api.yaml:6:15: randomInt takes 2 arguments, got 1"},
+		},
+		{
+			name: "condition",
+			config: "current_pipeline:\n  - endpoint: /a\n    if: \"status ==\"\n",
+			want: []string{"if: unexpected end of expression"},
+		},
+		{
+			name: "script",
+			config: "current_pipeline:\n  - endpoint: /a\n    preRequest: \"request[\\\"x\\\"] = undefined_name\"\n",
+			want: []string{"api.yaml:7:17: preRequest:1:16: undefined: undefined_name"},
+		},
+		{
+			name: "missing template",
+			config: "current_pipeline:\n  - endpoint: /a\n    extends: missing\n",
+			want: []string{"api.yaml:7:14: extends unknown template \"missing\""},
+		},
+		{
+			name: "reserved pipeline name",
+			config: "custom_pipelines:\n  current:\n    - endpoint: /a\n",
+			want: []string{"api.yaml:6:3: custom_pipelines: \"current\" is reserved"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := ParseYAML("api.yaml", []byte(header + test.config))
+			if err != nil { t.Fatalf("ParseYAML() returned %v", err) }
+
+			err = Validate(root)
+			if len(test.want) == 0 {
+				if err != nil { t.Errorf("Validate() returned %v", err) }
+				return
+			}
+			if err == nil { t.Fatalf("Validate() returned no error, want %q", test.want) }
+			for _, want := range test.want {
+				if !strings.Contains(err.Error(), want) { t.Errorf("Validate() returned\n%v\nwant it to hold %q", err, want) }
+			}
+		})
+	}
+}
+
+func TestValidateCertificates(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ca.pem"), []byte("not a certificate"), 0644); err != nil { t.Fatal(err) }
+
+	config := header + "environments:\n  development:\n    tls:\n      ca: ca.pem\n"
+	root, err := ParseYAML(filepath.Join(dir, "api.yaml"), []byte(config))
+	if err != nil { t.Fatalf("ParseYAML() returned %v", err) }
+
+	err = Validate(root)
+	if err == nil || !strings.Contains(err.Error(), "environments.development.tls") { t.Errorf("Validate() returned %v, want an error about the CA file", err) }
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlLine finds the line number inside the errors reported by yaml.v3
+var yamlLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// ParseYAML parses a yaml document into a tree of nodes
+func ParseYAML(file string, data []byte) (*Node, error) {
+	document := yaml.Node{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		if match := yamlLine.FindStringSubmatch(err.Error()); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			return nil, &Error{File: file, Line: line, Message: match[2]}
+		}
+		return nil, &Error{File: file, Message: err.Error()}
+	}
+
+	// an empty file is an empty object
+	if len(document.Content) == 0 { return &Node{Kind: Object, Fields: map[string]*Node{}, File: file, Line: 1, Column: 1}, nil }
+
+	return convertYAML(file, document.Content[0])
+}
+
+// convertYAML turns a yaml.v3 node into a configuration node
+func convertYAML(file string, source *yaml.Node) (*Node, error) {
+	n := &Node{File: file, Line: source.Line, Column: source.Column}
+
+	switch source.Kind {
+	case yaml.AliasNode:
+		return convertYAML(file, source.Alias)
+
+	case yaml.MappingNode:
+		n.Kind = Object
+		n.Fields = map[string]*Node{}
+		merged := map[string]bool{}
+		for i := 0; i + 1 < len(source.Content); i += 2 {
+			keySource, valueSource := source.Content[i], source.Content[i+1]
+
+			value, err := convertYAML(file, valueSource)
+			if err != nil { return nil, err }
+
+			// `<<: *anchor` merges the keys of another mapping
+			if keySource.Tag == "!!merge" {
+				if value.Kind != Object { return nil, errorAt(value, "only mappings can be merged") }
+				for _, key := range value.Keys {
+					if n.Fields[key.Value.(string)] == nil { n.set(key, value.Fields[key.Value.(string)]); merged[key.Value.(string)] = true }
+				}
+				continue
+			}
+
+			key := &Node{Kind: String, Value: keySource.Value, File: file, Line: keySource.Line, Column: keySource.Column}
+			if keySource.Kind != yaml.ScalarNode { return nil, errorAt(key, "keys must be plain strings") }
+			if n.Fields[keySource.Value] != nil && !merged[keySource.Value] { return nil, errorAt(key, "duplicate key %q", keySource.Value) }
+			delete(merged, keySource.Value)
+			n.set(key, value)
+		}
+
+	case yaml.SequenceNode:
+		n.Kind = Array
+		n.Items = []*Node{}
+		for _, itemSource := range source.Content {
+			item, err := convertYAML(file, itemSource)
+			if err != nil { return nil, err }
+			n.Items = append(n.Items, item)
+		}
+
+	case yaml.ScalarNode:
+		var value any
+		if err := source.Decode(&value); err != nil { return nil, errorAt(n, "%s", err.Error()) }
+
+		switch v := value.(type) {
+		case nil: n.Kind = Null
+		case bool: n.Kind, n.Value = Bool, v
+		case int: n.Kind, n.Value = Number, float64(v)
+		case int64: n.Kind, n.Value = Number, float64(v)
+		case uint64: n.Kind, n.Value = Number, float64(v)
+		case float64: n.Kind, n.Value = Number, v
+		case string: n.Kind, n.Value = String, v
+		default: n.Kind, n.Value = String, source.Value
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// CookieJar keeps the cookies set by the responses of a run and sends them
+// back with the requests that follow, like a browser would. It remembers
+// every cookie it was given so it can be copied and saved between runs
+type CookieJar struct {
+	jar *cookiejar.Jar
+	cookies []storedCookie
+}
+
+// storedCookie is a cookie along with the url that set it
+type storedCookie struct {
+	URL string `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// NewCookieJar makes an empty jar
+func NewCookieJar() *CookieJar {
+	jar, _ := cookiejar.New(nil)
+	return &CookieJar{jar: jar}
+}
+
+// SetCookies stores the cookies a response to the url set
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	for _, cookie := range cookies {
+		// a relative lifetime would start over once the jar is read again
+		if cookie.MaxAge > 0 {
+			cookie.Expires, cookie.MaxAge = time.Now().Add(time.Duration(cookie.MaxAge) * time.Second), 0
+		}
+
+		// a cookie set again replaces the earlier one, even when it is deleted
+		stored := storedCookie{URL: u.String(), Cookie: cookie}
+		kept := j.cookies[:0]
+		for _, earlier := range j.cookies {
+			if earlier.key() != stored.key() { kept = append(kept, earlier) }
+		}
+		j.cookies = append(kept, stored)
+	}
+}
+
+// key tells apart the cookies a jar holds: by name, domain and path
+func (s storedCookie) key() string {
+	domain := s.Cookie.Domain
+	if u, err := url.Parse(s.URL); domain == "" && err == nil { domain = u.Hostname() }
+	return s.Cookie.Name + ";" + strings.TrimPrefix(domain, ".") + ";" + s.Cookie.Path
+}
+
+// Cookies lists the cookies to send with a request to the url
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Copy makes a jar holding the same cookies, changes to either one are not seen by the other
+func (j *CookieJar) Copy() *CookieJar {
+	copied := NewCookieJar()
+	for _, stored := range j.cookies { copied.restore(stored) }
+	return copied
+}
+
+// restore puts back a cookie set earlier
+func (j *CookieJar) restore(stored storedCookie) {
+	u, err := url.Parse(stored.URL)
+	if err != nil { return }
+	j.SetCookies(u, []*http.Cookie{stored.Cookie})
+}
+
+// ReadCookieJar reads the jar an earlier run saved for an environment, a
+// missing file or environment gives an empty jar
+func ReadCookieJar(path string, environment string) (*CookieJar, error) {
+	jar := NewCookieJar()
+	saved, err := readCookieFile(path)
+	if err != nil { return nil, err }
+	for _, cookie := range saved[environment] {
+		if cookie.Cookie != nil { jar.restore(cookie) }
+	}
+	return jar, nil
+}
+
+// readCookieFile reads the jars of every environment saved in a file
+func readCookieFile(path string) (map[string][]storedCookie, error) {
+	saved := map[string][]storedCookie{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) { return saved, nil }
+	if err != nil { return nil, fmt.Errorf("Could not read cookie file %s", path) }
+
+	if err := json.Unmarshal(data, &saved); err != nil { return nil, fmt.Errorf("Could not parse cookie file %s: %s", path, err.Error()) }
+	return saved, nil
+}
+
+// Save writes the cookies still alive to a file under the environment, for
+// the next run to read. The jars of the other environments are kept
+func (j *CookieJar) Save(path string, environment string) error {
+	saved, err := readCookieFile(path)
+	if err != nil { return err }
+
+	alive := []storedCookie{}
+	for _, stored := range j.cookies {
+		expired := stored.Cookie.MaxAge < 0 || (!stored.Cookie.Expires.IsZero() && stored.Cookie.Expires.Before(time.Now()))
+		if !expired { alive = append(alive, stored) }
+	}
+	saved[environment] = alive
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil { return err }
+	if err := os.WriteFile(path, data, 0600); err != nil { return fmt.Errorf("Could not write cookie file %s", path) }
+	return nil
+}
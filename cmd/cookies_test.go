@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// cookieValues lists the cookies a jar sends to a url as name=value
+func cookieValues(jar *CookieJar, location string) map[string]string {
+	u, _ := url.Parse(location)
+	values := map[string]string{}
+	for _, cookie := range jar.Cookies(u) { values[cookie.Name] = cookie.Value }
+	return values
+}
+
+func TestCookieJarSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	api, _ := url.Parse("http://api.test/login")
+
+	development := NewCookieJar()
+	development.SetCookies(api, []*http.Cookie{
+		{Name: "sid", Value: "dev", Path: "/"},
+		{Name: "remember", Value: "yes", Path: "/", MaxAge: 3600},
+		{Name: "gone", Value: "x", Path: "/", Expires: time.Now().Add(-time.Hour)},
+	})
+	if err := development.Save(path, "development"); err != nil { t.Fatal(err) }
+
+	production := NewCookieJar()
+	production.SetCookies(api, []*http.Cookie{{Name: "sid", Value: "prod", Path: "/"}})
+	if err := production.Save(path, "production"); err != nil { t.Fatal(err) }
+
+	// every environment reads back its own cookies, the expired ones are dropped
+	// and a relative lifetime counts from when the cookie was set
+	for environment, want := range map[string]map[string]string{
+		"development": {"sid": "dev", "remember": "yes"},
+		"production": {"sid": "prod"},
+		"staging": {},
+	} {
+		jar, err := ReadCookieJar(path, environment)
+		if err != nil { t.Fatal(err) }
+		got := cookieValues(jar, "http://api.test/me")
+		if len(got) != len(want) { t.Errorf("the %s jar sends %v, want %v", environment, got, want); continue }
+		for name, value := range want {
+			if got[name] != value { t.Errorf("the %s jar sends %v, want %v", environment, got, want) }
+		}
+	}
+
+	// a cookie deleted by a response is not saved again
+	development.SetCookies(api, []*http.Cookie{{Name: "sid", Value: "", Path: "/", MaxAge: -1}})
+	if err := development.Save(path, "development"); err != nil { t.Fatal(err) }
+	jar, _ := ReadCookieJar(path, "development")
+	if got := cookieValues(jar, "http://api.test/"); got["sid"] != "" || got["remember"] != "yes" { t.Errorf("the development jar sends %v after sid was deleted", got) }
+
+	if _, err := ReadCookieJar(filepath.Join(t.TempDir(), "missing.json"), "development"); err != nil { t.Errorf("ReadCookieJar() of a missing file returned %v", err) }
+}
+
+func TestCookieJarCopy(t *testing.T) {
+	api, _ := url.Parse("http://api.test/")
+	jar := NewCookieJar()
+	jar.SetCookies(api, []*http.Cookie{{Name: "sid", Value: "a"}})
+
+	// the copy starts with the cookies and goes its own way
+	copied := jar.Copy()
+	copied.SetCookies(api, []*http.Cookie{{Name: "sid", Value: "b"}, {Name: "cart", Value: "1"}})
+	if got := cookieValues(jar, "http://api.test/"); len(got) != 1 || got["sid"] != "a" { t.Errorf("the jar sends %v after its copy changed", got) }
+	if got := cookieValues(copied, "http://api.test/"); got["sid"] != "b" || got["cart"] != "1" { t.Errorf("the copy sends %v", got) }
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Settings returns the settings of an environment by its name
+func (e EnvironmentSettings) Settings(env string) Settings {
+	switch env {
+	case "staging": return e.Staging
+	case "production": return e.Production
+	}
+	return e.Development
+}
+
+// ActiveDefaults merges the defaults of the active environment over the root defaults
+func (s *Structure) ActiveDefaults() Defaults {
+	env := s.Environments.Settings(s.ActiveEnvironment).Defaults
+
+	merged := Defaults{Headers: map[string]string{}, Query: map[string]any{}, Timeout: s.Defaults.Timeout}
+	for key, value := range s.Defaults.Headers { merged.Headers[key] = value }
+	for key, value := range env.Headers { merged.Headers[key] = value }
+	for key, value := range s.Defaults.Query { merged.Query[key] = value }
+	for key, value := range env.Query { merged.Query[key] = value }
+	if env.Timeout != "" { merged.Timeout = env.Timeout }
+
+	return merged
+}
+
+// ActiveTLS returns the TLS settings of the active environment
+func (s *Structure) ActiveTLS() TLS {
+	return s.Environments.Settings(s.ActiveEnvironment).TLS
+}
+
+// ActiveTransport returns the transport settings of the active environment
+func (s *Structure) ActiveTransport() TransportSettings {
+	return s.Environments.Settings(s.ActiveEnvironment).Transport
+}
+
+// MarshalJSON leaves out the blocks that are not set, json only omits
+// empty structs when they are pointers
+func (s Structure) MarshalJSON() ([]byte, error) {
+	type plain Structure
+	return json.Marshal(struct {
+		plain
+		Defaults *Defaults `json:"defaults,omitempty"`
+		Environments *EnvironmentSettings `json:"environments,omitempty"`
+		Cookies *CookieSettings `json:"cookieJar,omitempty"`
+		Baseline *Baseline `json:"baseline,omitempty"`
+	}{plain(s), ifSet(s.Defaults), ifSet(s.Environments), ifSet(s.Cookies), ifSet(s.Baseline)})
+}
+
+// MarshalJSON leaves out the environments that have no settings
+func (e EnvironmentSettings) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Development *Settings `json:"development,omitempty"`
+		Staging *Settings `json:"staging,omitempty"`
+		Production *Settings `json:"production,omitempty"`
+	}{ifSet(e.Development), ifSet(e.Staging), ifSet(e.Production)})
+}
+
+// MarshalJSON leaves out the blocks of an environment that are not set
+func (s Settings) MarshalJSON() ([]byte, error) {
+	type plain Settings
+	return json.Marshal(struct {
+		plain
+		Defaults *Defaults `json:"defaults,omitempty"`
+		TLS *TLS `json:"tls,omitempty"`
+		Transport *TransportSettings `json:"transport,omitempty"`
+	}{plain(s), ifSet(s.Defaults), ifSet(s.TLS), ifSet(s.Transport)})
+}
+
+// ifSet points to a value, nil when it is the zero value
+func ifSet[T any](value T) *T {
+	if reflect.ValueOf(value).IsZero() { return nil }
+	return &value
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestActiveDefaults(t *testing.T) {
+	fileContents := &Structure{
+		Defaults: Defaults{Headers: map[string]string{"Accept": "application/json", "X-Team": "core"}, Query: map[string]any{"lang": "en"}, Timeout: "5s"},
+		Environments: EnvironmentSettings{
+			Staging: Settings{Defaults: Defaults{Headers: map[string]string{"X-Team": "qa"}, Query: map[string]any{"debug": true}}},
+			Production: Settings{Defaults: Defaults{Timeout: "1s"}},
+		},
+	}
+
+	// the environment wins key by key and keeps what it does not set
+	want := map[string]Defaults{
+		"development": {Headers: map[string]string{"Accept": "application/json", "X-Team": "core"}, Query: map[string]any{"lang": "en"}, Timeout: "5s"},
+		"staging": {Headers: map[string]string{"Accept": "application/json", "X-Team": "qa"}, Query: map[string]any{"lang": "en", "debug": true}, Timeout: "5s"},
+		"production": {Headers: map[string]string{"Accept": "application/json", "X-Team": "core"}, Query: map[string]any{"lang": "en"}, Timeout: "1s"},
+	}
+	for env, defaults := range want {
+		fileContents.ActiveEnvironment = env
+		if got := fileContents.ActiveDefaults(); !reflect.DeepEqual(got, defaults) { t.Errorf("ActiveDefaults() in %s = %+v, want %+v", env, got, defaults) }
+	}
+
+	// merging never writes into the configuration
+	if fileContents.Defaults.Headers["X-Team"] != "core" || len(fileContents.Defaults.Query) != 1 { t.Errorf("ActiveDefaults() changed the root defaults to %+v", fileContents.Defaults) }
+}
+
+func TestMarshalEmptyBlocks(t *testing.T) {
+	fileContents := Structure{
+		BaseURL: Environments{Development: "http://localhost"},
+		Environments: EnvironmentSettings{Staging: Settings{Transport: TransportSettings{Proxy: "http://proxy:3128"}}},
+	}
+
+	data, err := json.Marshal(fileContents)
+	if err != nil { t.Fatal(err) }
+
+	want := `{"baseUrl":{"development":"http://localhost"},"credentials":{},"loginDetails":{"route":"","token_location":""},` +
+		`"environments":{"staging":{"transport":{"proxy":"http://proxy:3128"}}}}`
+	if string(data) != want { t.Errorf("json.Marshal() =\n%s\nwant\n%s", data, want) }
+
+	// what is written reads back the same
+	read := Structure{}
+	if err := json.Unmarshal(data, &read); err != nil { t.Fatal(err) }
+	if !reflect.DeepEqual(read, fileContents) { t.Errorf("json.Unmarshal() read back %+v", read) }
+}
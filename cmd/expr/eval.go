@@ -0,0 +1,298 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// node is a parsed part of an expression
+type node interface {
+	eval(env map[string]any) (any, error)
+}
+
+type literal struct{ value any }
+
+type list struct{ items []node }
+
+type name struct{ name string }
+
+type member struct {
+	object node
+	name string
+}
+
+type index struct {
+	object node
+	key node
+}
+
+type unary struct {
+	operator string
+	operand node
+}
+
+type binary struct {
+	operator string
+	left node
+	right node
+}
+
+type call struct {
+	function string
+	arguments []node
+}
+
+func (n literal) eval(env map[string]any) (any, error) { return n.value, nil }
+
+func (n list) eval(env map[string]any) (any, error) {
+	values := make([]any, len(n.items))
+	for i, item := range n.items {
+		value, err := item.eval(env)
+		if err != nil { return nil, err }
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (n name) eval(env map[string]any) (any, error) {
+	value, exists := env[n.name]
+	if !exists { return nil, fmt.Errorf("unknown name %q", n.name) }
+	return normalize(value), nil
+}
+
+// member reads a field, a missing field is null so conditions can test for it
+func (n member) eval(env map[string]any) (any, error) {
+	object, err := n.object.eval(env)
+	if err != nil { return nil, err }
+	return field(object, n.name), nil
+}
+
+func (n index) eval(env map[string]any) (any, error) {
+	object, err := n.object.eval(env)
+	if err != nil { return nil, err }
+	key, err := n.key.eval(env)
+	if err != nil { return nil, err }
+
+	if position, isNumber := key.(float64); isNumber {
+		list, isList := object.([]any)
+		if !isList || position != math.Trunc(position) || position < 0 || int(position) >= len(list) { return nil, nil }
+		return normalize(list[int(position)]), nil
+	}
+	return field(object, fmt.Sprint(key)), nil
+}
+
+func (n unary) eval(env map[string]any) (any, error) {
+	operand, err := n.operand.eval(env)
+	if err != nil { return nil, err }
+
+	switch value := operand.(type) {
+	case bool:
+		if n.operator == "!" { return !value, nil }
+	case float64:
+		if n.operator == "-" { return -value, nil }
+	case time.Duration:
+		if n.operator == "-" { return -value, nil }
+	}
+	return nil, fmt.Errorf("cannot apply %s to %s", n.operator, kindOf(operand))
+}
+
+func (n binary) eval(env map[string]any) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil { return nil, err }
+
+	// && and || only read their right side when it matters
+	if n.operator == "&&" || n.operator == "||" {
+		condition, isBool := left.(bool)
+		if !isBool { return nil, fmt.Errorf("%s needs booleans, got %s", n.operator, kindOf(left)) }
+		if condition == (n.operator == "||") { return condition, nil }
+
+		right, err := n.right.eval(env)
+		if err != nil { return nil, err }
+		if _, isBool := right.(bool); !isBool { return nil, fmt.Errorf("%s needs booleans, got %s", n.operator, kindOf(right)) }
+		return right, nil
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil { return nil, err }
+
+	switch n.operator {
+	case "==": return reflect.DeepEqual(left, right), nil
+	case "!=": return !reflect.DeepEqual(left, right), nil
+	case "contains": return contains(left, right)
+	case "in": return contains(right, left)
+	case "matches":
+		text, isText := left.(string)
+		pattern, isPattern := right.(string)
+		if !isText || !isPattern { return nil, fmt.Errorf("matches needs strings, got %s and %s", kindOf(left), kindOf(right)) }
+		matcher, err := regexp.Compile(pattern)
+		if err != nil { return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err) }
+		return matcher.MatchString(text), nil
+	case "<", "<=", ">", ">=":
+		return compare(n.operator, left, right)
+	}
+	return arithmetic(n.operator, left, right)
+}
+
+func (n call) eval(env map[string]any) (any, error) {
+	arguments := make([]any, len(n.arguments))
+	for i, argument := range n.arguments {
+		value, err := argument.eval(env)
+		if err != nil { return nil, err }
+		arguments[i] = value
+	}
+	return functions[n.function](arguments)
+}
+
+// functions callable from an expression
+var functions = map[string]func([]any) (any, error){
+	"len": func(arguments []any) (any, error) {
+		if len(arguments) != 1 { return nil, fmt.Errorf("len takes 1 argument, got %d", len(arguments)) }
+		switch value := arguments[0].(type) {
+		case string: return float64(len([]rune(value))), nil
+		case []any: return float64(len(value)), nil
+		case map[string]any: return float64(len(value)), nil
+		case nil: return float64(0), nil
+		}
+		return nil, fmt.Errorf("len needs a string, array or object, got %s", kindOf(arguments[0]))
+	},
+	"lower": func(arguments []any) (any, error) {
+		if len(arguments) != 1 { return nil, fmt.Errorf("lower takes 1 argument, got %d", len(arguments)) }
+		text, isText := arguments[0].(string)
+		if !isText { return nil, fmt.Errorf("lower needs a string, got %s", kindOf(arguments[0])) }
+		return strings.ToLower(text), nil
+	},
+	"exists": func(arguments []any) (any, error) {
+		if len(arguments) != 1 { return nil, fmt.Errorf("exists takes 1 argument, got %d", len(arguments)) }
+		return arguments[0] != nil, nil
+	},
+}
+
+// field reads a key of an object, headers are read without minding the case
+func field(object any, key string) any {
+	switch value := object.(type) {
+	case map[string]any:
+		return normalize(value[key])
+	case http.Header:
+		if values := value.Values(key); len(values) > 0 { return values[0] }
+	}
+	return nil
+}
+
+// contains tells if a string holds a substring, an array an item or an object a key
+func contains(container any, item any) (any, error) {
+	switch value := container.(type) {
+	case string:
+		text, isText := item.(string)
+		if !isText { return nil, fmt.Errorf("contains needs a string to look for in a string, got %s", kindOf(item)) }
+		return strings.Contains(value, text), nil
+	case []any:
+		for _, element := range value {
+			if reflect.DeepEqual(normalize(element), item) { return true, nil }
+		}
+		return false, nil
+	case map[string]any:
+		_, exists := value[fmt.Sprint(item)]
+		return exists, nil
+	case nil:
+		return false, nil
+	}
+	return nil, fmt.Errorf("contains needs a string, array or object, got %s", kindOf(container))
+}
+
+// compare orders numbers, strings and durations
+func compare(operator string, left any, right any) (any, error) {
+	var order int
+	switch a := left.(type) {
+	case float64:
+		b, ok := right.(float64)
+		if !ok { return nil, mismatch(operator, left, right) }
+		order = cmpOf(a < b, a > b)
+	case string:
+		b, ok := right.(string)
+		if !ok { return nil, mismatch(operator, left, right) }
+		order = strings.Compare(a, b)
+	case time.Duration:
+		b, ok := right.(time.Duration)
+		if !ok { return nil, mismatch(operator, left, right) }
+		order = cmpOf(a < b, a > b)
+	default:
+		return nil, mismatch(operator, left, right)
+	}
+
+	switch operator {
+	case "<": return order < 0, nil
+	case "<=": return order <= 0, nil
+	case ">": return order > 0, nil
+	}
+	return order >= 0, nil
+}
+
+// arithmetic adds, subtracts, multiplies and divides numbers and durations,
+// + joins strings as well
+func arithmetic(operator string, left any, right any) (any, error) {
+	switch a := left.(type) {
+	case float64:
+		b, ok := right.(float64)
+		if !ok { return nil, mismatch(operator, left, right) }
+		switch operator {
+		case "+": return a + b, nil
+		case "-": return a - b, nil
+		case "*": return a * b, nil
+		case "/":
+			if b == 0 { return nil, fmt.Errorf("division by zero") }
+			return a / b, nil
+		case "%":
+			if b == 0 { return nil, fmt.Errorf("division by zero") }
+			return math.Mod(a, b), nil
+		}
+	case string:
+		b, ok := right.(string)
+		if ok && operator == "+" { return a + b, nil }
+	case time.Duration:
+		if b, ok := right.(time.Duration); ok && operator == "+" { return a + b, nil }
+		if b, ok := right.(time.Duration); ok && operator == "-" { return a - b, nil }
+		if b, ok := right.(float64); ok && operator == "*" { return time.Duration(float64(a) * b), nil }
+		if b, ok := right.(float64); ok && operator == "/" && b != 0 { return time.Duration(float64(a) / b), nil }
+	}
+	return nil, mismatch(operator, left, right)
+}
+
+func cmpOf(less bool, greater bool) int {
+	if less { return -1 }
+	if greater { return 1 }
+	return 0
+}
+
+func mismatch(operator string, left any, right any) error {
+	return fmt.Errorf("cannot apply %s to %s and %s", operator, kindOf(left), kindOf(right))
+}
+
+// normalize turns the go numbers found in variables into the float64 of json
+func normalize(value any) any {
+	switch number := value.(type) {
+	case int: return float64(number)
+	case int32: return float64(number)
+	case int64: return float64(number)
+	case float32: return float64(number)
+	}
+	return value
+}
+
+// kindOf names the type of a value in error messages
+func kindOf(value any) string {
+	switch value.(type) {
+	case nil: return "null"
+	case bool: return "boolean"
+	case float64: return "number"
+	case string: return "string"
+	case time.Duration: return "duration"
+	case []any: return "array"
+	case map[string]any, http.Header: return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,93 @@
+// Package expr evaluates the small expressions used by steps to wait for
+// and check responses, such as `body.status == "done" && len(body.items) > 0`.
+// Expressions read names from an environment, walk into objects with `.`
+// and `[]`, compare numbers, strings and durations like `300ms`, and use
+// `contains`, `matches`, `in` and the functions len, lower and exists
+package expr
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Expression is a parsed expression ready to be evaluated many times
+type Expression struct {
+	Source string
+	root node
+}
+
+// Parse compiles an expression, reporting the column of syntax errors
+func Parse(source string) (*Expression, error) {
+	tokens, err := lex(source)
+	if err != nil { return nil, err }
+
+	p := &parser{tokens: tokens}
+	root, err := p.expression(0)
+	if err != nil { return nil, err }
+	if end := p.peek(); end.kind != "end" { return nil, fmt.Errorf("unexpected %s at column %d", end.text, end.column) }
+
+	return &Expression{Source: source, root: root}, nil
+}
+
+// Eval computes the value of the expression against an environment
+func (e *Expression) Eval(env map[string]any) (any, error) {
+	value, err := e.root.eval(env)
+	if err != nil { return nil, fmt.Errorf("%s: %v", e.Source, err) }
+	return value, nil
+}
+
+// Bool evaluates an expression that has to result in a boolean
+func (e *Expression) Bool(env map[string]any) (bool, error) {
+	value, err := e.Eval(env)
+	if err != nil { return false, err }
+
+	result, isBool := value.(bool)
+	if !isBool { return false, fmt.Errorf("%s: expected a boolean, got %s", e.Source, kindOf(value)) }
+	return result, nil
+}
+
+// Environment holds the names a step expression can read: every variable
+// by its name and under `vars`. Responses add their `status`, `body`,
+// `headers` and the `cookies` they set on top
+func Environment(variables map[string]any, status int, body any, headers http.Header) map[string]any {
+	env := map[string]any{}
+	for key, value := range variables { env[key] = value }
+	env["vars"] = variables
+
+	if headers == nil { headers = http.Header{} }
+	cookies := map[string]any{}
+	for _, cookie := range (&http.Response{Header: headers}).Cookies() { cookies[cookie.Name] = cookie.Value }
+	env["status"], env["body"], env["headers"], env["cookies"] = float64(status), body, headers, cookies
+	return env
+}
+
+// Names lists the variables an expression reads, so steps can be ordered
+// after the ones capturing them
+func (e *Expression) Names() []string {
+	names := []string{}
+	walk(e.root, func(n node) {
+		if found, isName := n.(name); isName { names = append(names, found.name) }
+	})
+	return names
+}
+
+// walk calls fn for a node and every node below it
+func walk(n node, fn func(node)) {
+	fn(n)
+	switch value := n.(type) {
+	case list:
+		for _, item := range value.items { walk(item, fn) }
+	case member:
+		walk(value.object, fn)
+	case index:
+		walk(value.object, fn)
+		walk(value.key, fn)
+	case unary:
+		walk(value.operand, fn)
+	case binary:
+		walk(value.left, fn)
+		walk(value.right, fn)
+	case call:
+		for _, argument := range value.arguments { walk(argument, fn) }
+	}
+}
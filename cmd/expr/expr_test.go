@@ -0,0 +1,120 @@
+package expr
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEnvironment is a response as steps see it, along with a few variables
+func testEnvironment() map[string]any {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Add("Set-Cookie", "session=abc; Path=/")
+
+	body := map[string]any{
+		"status": "done",
+		"items": []any{map[string]any{"id": float64(1)}, map[string]any{"id": float64(2)}},
+		"name": "Jane",
+		"empty": nil,
+	}
+	env := Environment(map[string]any{"userId": 2, "limit": float64(10)}, 200, body, headers)
+	env["duration"] = map[string]any{"total": 250 * time.Millisecond, "ttfb": 90 * time.Millisecond}
+	return env
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		source string
+		want any
+	}{
+		{"status == 200", true},
+		{"body.status == \"done\" && len(body.items) > 0", true},
+		{"body.items[1].id", float64(2)},
+		{"body.items.0.id", float64(1)},
+		{"body.items[0].id == userId", false},
+		{"userId in [1, 2, 3]", true},
+		{"vars.limit * 2", float64(20)},
+		{"headers[\"content-type\"] contains \"json\"", true},
+		{"cookies.session == 'abc'", true},
+		{"body.name matches \"^J\"", true},
+		{"lower(body.name) + \"!\"", "jane!"},
+		{"exists(body.missing) || !exists(body.empty)", true},
+		{"len(body.missing)", float64(0)},
+		{"duration.total < 300ms", true},
+		{"duration.ttfb + 10ms >= 100ms", true},
+		{"duration.total / 2", 125 * time.Millisecond},
+		{"7 % 4 - 1", float64(2)},
+		{"body contains \"items\"", true},
+		{"(1 + 2) * 3", float64(9)},
+	}
+
+	env := testEnvironment()
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			expression, err := Parse(test.source)
+			if err != nil { t.Fatalf("Parse() returned %v", err) }
+
+			got, err := expression.Eval(env)
+			if err != nil { t.Fatalf("Eval() returned %v", err) }
+			if !reflect.DeepEqual(got, test.want) { t.Errorf("Eval() = %#v, want %#v", got, test.want) }
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want string
+	}{
+		{"status ==", "at column 10"},
+		{"body.name == \"open", "unterminated string at column 14"},
+		{"12abc > 1", "invalid number \"12abc\" at column 1"},
+		{"status @ 1", "unexpected \"@\" at column 8"},
+		{"status 200", "unexpected 200 at column 8"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.source)
+		if err == nil || !strings.Contains(err.Error(), test.want) { t.Errorf("Parse(%q) returned %v, want an error %s", test.source, err, test.want) }
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want string
+	}{
+		{"body.name > 1", "cannot apply > to string and number"},
+		{"status && true", "&& needs booleans, got number"},
+		{"1 / 0", "division by zero"},
+		{"body.name matches \"[\"", "invalid pattern"},
+		{"len(1)", "len needs a string, array or object, got number"},
+	}
+
+	env := testEnvironment()
+	for _, test := range tests {
+		expression, err := Parse(test.source)
+		if err != nil { t.Errorf("Parse(%q) returned %v", test.source, err); continue }
+
+		_, err = expression.Eval(env)
+		if err == nil || !strings.Contains(err.Error(), test.want) { t.Errorf("Eval(%q) returned %v, want an error about %q", test.source, err, test.want) }
+	}
+}
+
+func TestBool(t *testing.T) {
+	expression, err := Parse("body.status")
+	if err != nil { t.Fatal(err) }
+
+	if _, err := expression.Bool(testEnvironment()); err == nil || !strings.Contains(err.Error(), "expected a boolean, got string") { t.Errorf("Bool() returned %v, want an error about the string", err) }
+}
+
+func TestNames(t *testing.T) {
+	expression, err := Parse("body.id == userId && len(items[index]) > limit")
+	if err != nil { t.Fatal(err) }
+
+	want := []string{"body", "userId", "items", "index", "limit"}
+	if got := expression.Names(); !reflect.DeepEqual(got, want) { t.Errorf("Names() = %q, want %q", got, want) }
+}
@@ -0,0 +1,244 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// token is a single lexeme of an expression along with its column
+type token struct {
+	kind string
+	text string
+	value any
+	column int
+}
+
+// operators are matched longest first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ".", ","}
+
+// words are the operators written as words, the others are names
+var words = map[string]bool{"contains": true, "matches": true, "in": true}
+
+// lex splits an expression into tokens
+func lex(source string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		column := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsDigit(r) && len(tokens) > 0 && tokens[len(tokens) - 1].text == ".":
+			// numeric segments of a path such as items.0.id
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) { i++ }
+			number, _ := strconv.ParseFloat(string(runes[start:i]), 64)
+			tokens = append(tokens, token{kind: "literal", text: string(runes[start:i]), value: number, column: column})
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || unicode.IsLetter(runes[i])) { i++ }
+			text := string(runes[start:i])
+
+			// numbers followed by a unit are durations, such as 300ms or 1m30s
+			if number, err := strconv.ParseFloat(text, 64); err == nil {
+				tokens = append(tokens, token{kind: "literal", text: text, value: number, column: column})
+			} else if duration, err := time.ParseDuration(text); err == nil {
+				tokens = append(tokens, token{kind: "literal", text: text, value: duration, column: column})
+			} else {
+				return nil, fmt.Errorf("invalid number %q at column %d", text, column)
+			}
+
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' { i++ }
+				i++
+			}
+			if i >= len(runes) { return nil, fmt.Errorf("unterminated string at column %d", column) }
+			i++
+
+			text := string(runes[start:i])
+			quoted := text
+			if r == '\'' { quoted = `"` + strings.ReplaceAll(strings.ReplaceAll(text[1:len(text) - 1], `\'`, `'`), `"`, `\"`) + `"` }
+			value, err := strconv.Unquote(quoted)
+			if err != nil { return nil, fmt.Errorf("invalid string %s at column %d", text, column) }
+			tokens = append(tokens, token{kind: "literal", text: text, value: value, column: column})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') { i++ }
+			text := string(runes[start:i])
+
+			switch {
+			case text == "true" || text == "false":
+				tokens = append(tokens, token{kind: "literal", text: text, value: text == "true", column: column})
+			case text == "null":
+				tokens = append(tokens, token{kind: "literal", text: text, value: nil, column: column})
+			case words[text]:
+				tokens = append(tokens, token{kind: "operator", text: text, column: column})
+			default:
+				tokens = append(tokens, token{kind: "name", text: text, column: column})
+			}
+
+		default:
+			matched := ""
+			for _, operator := range operators {
+				if strings.HasPrefix(string(runes[i:]), operator) { matched = operator; break }
+			}
+			if matched == "" { return nil, fmt.Errorf("unexpected %q at column %d", string(r), column) }
+
+			tokens = append(tokens, token{kind: "operator", text: matched, column: column})
+			i += len([]rune(matched))
+		}
+	}
+
+	return append(tokens, token{kind: "end", text: "end of expression", column: len(runes) + 1}), nil
+}
+
+// precedence of the binary operators, higher binds tighter
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4, "contains": 4, "matches": 4, "in": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+// parser is a precedence climbing parser over the tokens of an expression
+type parser struct {
+	tokens []token
+	position int
+}
+
+func (p *parser) peek() token { return p.tokens[p.position] }
+
+func (p *parser) next() token {
+	current := p.tokens[p.position]
+	if current.kind != "end" { p.position++ }
+	return current
+}
+
+func (p *parser) expect(text string) error {
+	if current := p.next(); current.text != text || current.kind == "literal" {
+		return fmt.Errorf("expected %q at column %d, got %s", text, current.column, current.text)
+	}
+	return nil
+}
+
+// expression parses operators binding tighter than the given precedence
+func (p *parser) expression(minimum int) (node, error) {
+	left, err := p.unary()
+	if err != nil { return nil, err }
+
+	for {
+		operator := p.peek()
+		level, isBinary := precedence[operator.text]
+		if operator.kind != "operator" || !isBinary || level <= minimum { return left, nil }
+		p.next()
+
+		right, err := p.expression(level)
+		if err != nil { return nil, err }
+		left = binary{operator: operator.text, left: left, right: right}
+	}
+}
+
+// unary parses negations and the member, index and call suffixes
+func (p *parser) unary() (node, error) {
+	if current := p.peek(); current.kind == "operator" && (current.text == "!" || current.text == "-") {
+		p.next()
+		operand, err := p.unary()
+		if err != nil { return nil, err }
+		return unary{operator: current.text, operand: operand}, nil
+	}
+
+	operand, err := p.primary()
+	if err != nil { return nil, err }
+
+	for {
+		current := p.peek()
+		if current.kind != "operator" { return operand, nil }
+
+		switch current.text {
+		case ".":
+			p.next()
+			name := p.next()
+			if _, isNumber := name.value.(float64); isNumber {
+				operand = index{object: operand, key: literal{value: name.value}}
+				continue
+			}
+			if name.kind == "end" || (name.kind == "operator" && !words[name.text]) {
+				return nil, fmt.Errorf("expected a name after \".\" at column %d, got %s", name.column, name.text)
+			}
+			operand = member{object: operand, name: name.text}
+
+		case "[":
+			p.next()
+			key, err := p.expression(0)
+			if err != nil { return nil, err }
+			if err := p.expect("]"); err != nil { return nil, err }
+			operand = index{object: operand, key: key}
+
+		default:
+			return operand, nil
+		}
+	}
+}
+
+// primary parses literals, lists, names, calls and parentheses
+func (p *parser) primary() (node, error) {
+	current := p.next()
+
+	switch current.kind {
+	case "literal":
+		return literal{value: current.value}, nil
+
+	case "name":
+		if next := p.peek(); next.kind != "operator" || next.text != "(" { return name{name: current.text}, nil }
+		p.next()
+
+		arguments := []node{}
+		for p.peek().text != ")" {
+			argument, err := p.expression(0)
+			if err != nil { return nil, err }
+			arguments = append(arguments, argument)
+			if p.peek().text != "," { break }
+			p.next()
+		}
+		if err := p.expect(")"); err != nil { return nil, err }
+
+		if _, exists := functions[current.text]; !exists { return nil, fmt.Errorf("unknown function %q at column %d", current.text, current.column) }
+		return call{function: current.text, arguments: arguments}, nil
+
+	case "operator":
+		if current.text == "[" {
+			items := []node{}
+			for p.peek().text != "]" {
+				item, err := p.expression(0)
+				if err != nil { return nil, err }
+				items = append(items, item)
+				if p.peek().text != "," { break }
+				p.next()
+			}
+			if err := p.expect("]"); err != nil { return nil, err }
+			return list{items: items}, nil
+		}
+		if current.text == "(" {
+			inner, err := p.expression(0)
+			if err != nil { return nil, err }
+			if err := p.expect(")"); err != nil { return nil, err }
+			return inner, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %s at column %d", current.text, current.column)
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"regexp"
+	"sort"
+)
+
+// CurrentPipelineName is the label used for the steps of `current_pipeline`
+const CurrentPipelineName = "current"
+
+// StepFilter narrows a run down to the steps selected by the user through
+// the --step, --tags and --grep flags. All the given conditions must hold
+type StepFilter struct {
+	Step string
+	Tags []string
+	Grep *regexp.Regexp
+}
+
+// SelectedStep is a step picked by a filter along with the pipeline it
+// lives in. Missing lists the variables it uses that nothing run before it
+// captures, the step cannot run without them
+type SelectedStep struct {
+	Pipeline string
+	Index int
+	Step PipelineBody
+	Missing []string
+}
+
+// Empty tells if no condition is set on the filter
+func (f StepFilter) Empty() bool {
+	return f.Step == "" && len(f.Tags) == 0 && f.Grep == nil
+}
+
+// Matches tells if a single step satisfies all the conditions of the filter
+func (f StepFilter) Matches(step PipelineBody) bool {
+	if f.Step != "" && step.Name != f.Step { return false }
+	if f.Grep != nil && !f.Grep.MatchString(step.Endpoint) { return false }
+	if len(f.Tags) == 0 { return true }
+
+	// a step needs at least one of the requested tags
+	for _, wanted := range f.Tags {
+		for _, tag := range step.Tags {
+			if tag == wanted { return true }
+		}
+	}
+	return false
+}
+
+// PipelineNames returns `current` followed by the custom pipelines in a stable order
+func (s *Structure) PipelineNames() []string {
+	names := make([]string, 0, len(s.CustomPipelines))
+	for name := range s.CustomPipelines { names = append(names, name) }
+	sort.Strings(names)
+
+	return append([]string{CurrentPipelineName}, names...)
+}
+
+// Pipeline returns the steps of a pipeline by its name
+func (s *Structure) Pipeline(name string) []PipelineBody {
+	if name == CurrentPipelineName { return s.PipelineBody }
+	return s.CustomPipelines[name].Steps
+}
+
+// SelectSteps walks every pipeline and picks the steps matching the filter.
+// Steps that capture the variables used by a selected step are pulled in as
+// well, from its own pipeline or else from a pipeline running before it, so
+// a filtered run still has everything it needs to interpolate. Variables
+// declared, read from the data file of the pipeline or captured by the
+// hooks, which run along with the selected steps, need no other step
+func (s *Structure) SelectSteps(filter StepFilter) []SelectedStep {
+	names := s.PipelineNames()
+	picked := make([][]bool, len(names))
+	missing := make([][][]string, len(names))
+	for p, name := range names {
+		picked[p], missing[p] = make([]bool, len(s.Pipeline(name))), make([][]string, len(s.Pipeline(name)))
+	}
+
+	var pick func(p int, index int)
+	pick = func(p int, index int) {
+		if picked[p][index] { return }
+		picked[p][index] = true
+
+		step := s.Pipeline(names[p])[index]
+		provided := s.provided(names[p], step)
+		for _, name := range StepReferences(step) {
+			if provided[name] { continue }
+
+			q, i := s.capturedBefore(names, p, index, name)
+			if q < 0 { missing[p][index] = appendOnce(missing[p][index], name); continue }
+			pick(q, i)
+		}
+	}
+
+	for p, name := range names {
+		for i, step := range s.Pipeline(name) {
+			if filter.Matches(step) { pick(p, i) }
+		}
+	}
+
+	selected := []SelectedStep{}
+	for p, name := range names {
+		for i, step := range s.Pipeline(name) {
+			if picked[p][i] { selected = append(selected, SelectedStep{Pipeline: name, Index: i, Step: step, Missing: missing[p][i]}) }
+		}
+	}
+	return selected
+}
+
+// provided lists the variables a step of a pipeline has without any other
+// step: the declared ones, the columns of the data file of the pipeline,
+// the captures of the hooks running before it or after the steps before it,
+// and the item of its forEach or the variables its preRequest stores
+func (s *Structure) provided(pipeline string, step PipelineBody) map[string]bool {
+	provided := map[string]bool{}
+	for name := range s.Variables { provided[name] = true }
+
+	hooks := [][]PipelineBody{s.BeforeAll, s.BeforeEach, s.AfterEach}
+	if custom, exists := s.CustomPipelines[pipeline]; exists && pipeline != CurrentPipelineName {
+		hooks = append(hooks, custom.BeforeAll, custom.BeforeEach, custom.AfterEach)
+
+		if custom.Data != "" {
+			rows, _ := ReadRows(s.Resolve(custom.Dir, custom.Data))
+			for _, row := range rows {
+				for name := range row { provided[name] = true }
+			}
+		}
+	}
+	for _, hook := range hooks {
+		for _, hookStep := range hook {
+			for name := range capturedBy(hookStep) { provided[name] = true }
+		}
+	}
+
+	if step.ForEach != "" {
+		as := step.As
+		if as == "" { as = "item" }
+		provided[as] = true
+	}
+	for _, name := range ScriptAssignments("preRequest", step.PreRequest) { provided[name] = true }
+	return provided
+}
+
+// capturedBefore finds the closest step capturing a variable before a step:
+// earlier in its own pipeline, else in the pipelines running before it. The
+// captures of a pipeline with a data file do not outlive its rows, so such
+// pipelines are passed over. A negative pipeline means none does
+func (s *Structure) capturedBefore(names []string, p int, index int, name string) (int, int) {
+	for q := p; q >= 0; q-- {
+		if q < p && s.CustomPipelines[names[q]].Data != "" && names[q] != CurrentPipelineName { continue }
+
+		steps := s.Pipeline(names[q])
+		last := len(steps) - 1
+		if q == p { last = index - 1 }
+		for i := last; i >= 0; i-- {
+			if capturedBy(steps[i])[name] { return q, i }
+		}
+	}
+	return -1, -1
+}
+
+// capturedBy lists the variables a step captures from its response, from
+// the messages of its stream or stores from its scripts
+func capturedBy(step PipelineBody) map[string]bool {
+	names := map[string]bool{}
+	for name := range step.Capture { names[name] = true }
+	for _, source := range []string{step.PreRequest, step.PostResponse} {
+		for _, name := range ScriptAssignments("script", source) { names[name] = true }
+	}
+	if step.Stream != nil {
+		for _, expectation := range step.Stream.Expect {
+			for name := range expectation.Capture { names[name] = true }
+		}
+	}
+	return names
+}
+
+// appendOnce adds a name to a list unless it is already there
+func appendOnce(list []string, name string) []string {
+	for _, item := range list {
+		if item == name { return list }
+	}
+	return append(list, name)
+}
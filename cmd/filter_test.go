@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestSelectSteps(t *testing.T) {
+	fileContents := &Structure{
+		PipelineBody: []PipelineBody{
+			{Name: "login", Endpoint: "/login", Capture: map[string]string{"session": "session"}},
+			{Name: "token", Endpoint: "/token", PostResponse: "vars[\"token\"] = response[\"body\"][\"token\"]"},
+			{Name: "user", Endpoint: "/users/1", Capture: map[string]string{"userId": "id"}, Tags: []string{"users"}},
+			{Name: "cart", Endpoint: "/cart", Cookies: map[string]any{"sid": "{{session}}"}},
+			{Name: "orders", Endpoint: "/orders", Headers: map[string]any{"Authorization": "{{token}}"}},
+			{Name: "profile", Endpoint: "/profile", ExpectedBody: map[string]any{"id": "{{userId}}"}},
+			{Name: "next", Endpoint: "/next", If: "userId > 0", Tags: []string{"users"}},
+		},
+		CustomPipelines: map[string]Pipeline{
+			"admin": {Steps: []PipelineBody{
+				{Name: "user", Endpoint: "/admin/users", Capture: map[string]string{"userId": "id"}},
+				{Name: "ban", Endpoint: "/admin/users/{{userId}}/ban", Tags: []string{"admin"}},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name string
+		filter StepFilter
+		want []string
+	}{
+		{name: "step", filter: StepFilter{Step: "orders"}, want: []string{"current/token", "current/orders"}},
+		{name: "cookies", filter: StepFilter{Step: "cart"}, want: []string{"current/login", "current/cart"}},
+		{name: "expected body is not interpolated", filter: StepFilter{Step: "profile"}, want: []string{"current/profile"}},
+		{name: "condition", filter: StepFilter{Step: "next"}, want: []string{"current/user", "current/next"}},
+		{name: "tags", filter: StepFilter{Tags: []string{"users", "admin"}}, want: []string{"current/user", "current/next", "admin/user", "admin/ban"}},
+		{name: "grep", filter: StepFilter{Grep: regexp.MustCompile("^/admin/users/")}, want: []string{"admin/user", "admin/ban"}},
+		{name: "step in two pipelines", filter: StepFilter{Step: "user"}, want: []string{"current/user", "admin/user"}},
+		{name: "every condition", filter: StepFilter{Step: "user", Tags: []string{"users"}}, want: []string{"current/user"}},
+		{name: "nothing", filter: StepFilter{Step: "missing"}, want: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []string{}
+			for _, selected := range fileContents.SelectSteps(test.filter) {
+				if selected.Step.Name != fileContents.Pipeline(selected.Pipeline)[selected.Index].Name { t.Errorf("SelectSteps() picked %s at the wrong index %d", selected.Step.Name, selected.Index) }
+				got = append(got, selected.Pipeline + "/" + selected.Step.Name)
+			}
+			if !reflect.DeepEqual(got, test.want) { t.Errorf("SelectSteps() = %q, want %q", got, test.want) }
+		})
+	}
+}
+
+func TestSelectStepsAcrossPipelines(t *testing.T) {
+	fileContents := &Structure{
+		Variables: map[string]any{"base": "/v1"},
+		Hooks: Hooks{BeforeAll: []PipelineBody{{Endpoint: "/login", Capture: map[string]string{"session": "id"}}}},
+		PipelineBody: []PipelineBody{
+			{Name: "account", Endpoint: "/account", Capture: map[string]string{"accountId": "id"}},
+		},
+		CustomPipelines: map[string]Pipeline{
+			"billing": {
+				Hooks: Hooks{BeforeEach: []PipelineBody{{Endpoint: "/nonce", PostResponse: "vars[\"nonce\"] = \"n\""}}},
+				Steps: []PipelineBody{
+					{Name: "invoice", Endpoint: "{{base}}/accounts/{{accountId}}/invoices", Headers: map[string]any{"X-Session": "{{session}}", "X-Nonce": "{{nonce}}"}},
+					{Name: "refund", Endpoint: "/refunds/{{refundId}}"},
+				},
+			},
+			"orders": {
+				Data: "orders.csv",
+				Steps: []PipelineBody{{Name: "order", Endpoint: "/orders", Capture: map[string]string{"orderId": "id"}}},
+			},
+			"shipping": {Steps: []PipelineBody{{Name: "ship", Endpoint: "/orders/{{orderId}}/ship"}}},
+		},
+	}
+
+	// the account of the current pipeline runs before billing, the session
+	// and the nonce come from hooks and base is declared
+	selected := fileContents.SelectSteps(StepFilter{Step: "invoice"})
+	if len(selected) != 2 || selected[0].Step.Name != "account" || selected[1].Pipeline != "billing" || len(selected[1].Missing) != 0 {
+		t.Errorf("SelectSteps(invoice) = %+v, want account then invoice with nothing missing", selected)
+	}
+
+	// nothing captures the refund, and orders runs once per row so its
+	// captures are gone by the time shipping runs
+	for _, step := range []string{"refund", "ship"} {
+		selected := fileContents.SelectSteps(StepFilter{Step: step})
+		if len(selected) != 1 || len(selected[0].Missing) != 1 { t.Errorf("SelectSteps(%s) = %+v, want it alone with a missing variable", step, selected) }
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// random is the source of every generated value, --seed replaces it to
+// make the values of a run reproducible
+var random = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// SeedGenerators makes the values of the random generators reproducible
+func SeedGenerators(seed int64) {
+	random = rand.New(rand.NewSource(seed))
+}
+
+// generator is a function usable in a placeholder, such as `{{randomInt 1 100}}`
+type generator struct {
+	min int
+	max int
+	run func(args []any) (any, error)
+}
+
+// generators are looked up when a placeholder does not name a variable
+var generators = map[string]generator{
+	"uuid": {0, 0, func(args []any) (any, error) {
+		high, low := random.Uint64(), random.Uint64()
+
+		// setting the version 4 and the RFC 4122 variant bits
+		high = high & ^uint64(0xf000) | 0x4000
+		low = low & ^(uint64(0xc0) << 56) | uint64(0x80) << 56
+		return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", high >> 32, high >> 16 & 0xffff, high & 0xffff, low >> 48, low & 0xffffffffffff), nil
+	}},
+	"randomEmail": {0, 0, func(args []any) (any, error) {
+		return "user." + randomText(8, "abcdefghijklmnopqrstuvwxyz0123456789") + "@example.com", nil
+	}},
+	"randomName": {0, 0, func(args []any) (any, error) {
+		return firstNames[random.Intn(len(firstNames))] + " " + lastNames[random.Intn(len(lastNames))], nil
+	}},
+	"randomString": {0, 1, func(args []any) (any, error) {
+		length := 12
+		if len(args) == 1 {
+			number, err := integerOf(args[0])
+			if err != nil || number < 1 { return nil, fmt.Errorf("randomString needs a positive length, got %v", args[0]) }
+			length = number
+		}
+		return randomText(length, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"), nil
+	}},
+	"randomInt": {2, 2, func(args []any) (any, error) {
+		low, err := integerOf(args[0])
+		if err != nil { return nil, err }
+		high, err := integerOf(args[1])
+		if err != nil { return nil, err }
+		if high < low { return nil, fmt.Errorf("randomInt needs its minimum first, got %d and %d", low, high) }
+		return low + random.Intn(high - low + 1), nil
+	}},
+	"now": {0, 1, func(args []any) (any, error) {
+		layout := "RFC3339"
+		if len(args) == 1 { layout = fmt.Sprint(args[0]) }
+
+		now := time.Now()
+		switch layout {
+		case "unix": return now.Unix(), nil
+		case "unixMilli": return now.UnixMilli(), nil
+		}
+		if named, exists := timeLayouts[layout]; exists { layout = named }
+		return now.Format(layout), nil
+	}},
+	"base64": {1, 1, func(args []any) (any, error) {
+		return base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(args[0]))), nil
+	}},
+	"hmac": {3, 3, func(args []any) (any, error) {
+		algorithms := map[string]func() hash.Hash{"sha1": sha1.New, "sha256": sha256.New, "sha512": sha512.New}
+		algorithm, exists := algorithms[strings.ToLower(fmt.Sprint(args[0]))]
+		if !exists { return nil, fmt.Errorf("hmac needs sha1, sha256 or sha512, got %v", args[0]) }
+
+		mac := hmac.New(algorithm, []byte(fmt.Sprint(args[1])))
+		mac.Write([]byte(fmt.Sprint(args[2])))
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	}},
+}
+
+// timeLayouts are the layouts `now` knows by name, any other is used as a go layout
+var timeLayouts = map[string]string{
+	"RFC3339": time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123": time.RFC1123,
+	"RFC822": time.RFC822,
+	"DateTime": time.DateTime,
+	"DateOnly": time.DateOnly,
+	"TimeOnly": time.TimeOnly,
+	"Kitchen": time.Kitchen,
+}
+
+var firstNames = []string{"Ann", "Bob", "Carla", "David", "Emma", "Farid", "Grace", "Hassan", "Ines", "John", "Kim", "Laila"}
+
+var lastNames = []string{"Ahmed", "Brown", "Chen", "Doe", "Evans", "Garcia", "Khan", "Lopez", "Miller", "Nguyen", "Smith", "Taylor"}
+
+// randomText picks length characters out of the given alphabet
+func randomText(length int, alphabet string) string {
+	text := make([]byte, length)
+	for i := range text { text[i] = alphabet[random.Intn(len(alphabet))] }
+	return string(text)
+}
+
+// integerOf reads a whole number out of an argument
+func integerOf(value any) (int, error) {
+	switch number := value.(type) {
+	case float64:
+		if number == float64(int(number)) { return int(number), nil }
+	case int:
+		return number, nil
+	case string:
+		if parsed, err := strconv.Atoi(number); err == nil { return parsed, nil }
+	}
+	return 0, fmt.Errorf("expected a whole number, got %v", value)
+}
+
+// CheckGenerator tells if a placeholder calling a generator has a known
+// function and the right number of arguments
+func CheckGenerator(name string, count int) error {
+	generator, exists := generators[name]
+	if !exists { return fmt.Errorf("unknown function %q", name) }
+	if count < generator.min || count > generator.max {
+		if generator.min == generator.max { return fmt.Errorf("%s takes %d %s, got %d", name, generator.min, arguments(generator.min), count) }
+		return fmt.Errorf("%s takes %d to %d arguments, got %d", name, generator.min, generator.max, count)
+	}
+	return nil
+}
+
+// Generate calls a generator by name, for the scripts of steps
+func Generate(name string, args []any) (any, error) {
+	if err := CheckGenerator(name, len(args)); err != nil { return nil, err }
+	return generators[name].run(args)
+}
+
+// GeneratorNames lists the generators in order
+func GeneratorNames() []string {
+	names := make([]string, 0, len(generators))
+	for name := range generators { names = append(names, name) }
+	sort.Strings(names)
+	return names
+}
+
+func arguments(count int) string {
+	if count == 1 { return "argument" }
+	return "arguments"
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSeedGenerators(t *testing.T) {
+	t.Cleanup(func() { SeedGenerators(time.Now().UnixNano()) })
+	body := map[string]any{
+		"id": "{{uuid}}",
+		"email": "{{randomEmail}}",
+		"user": map[string]any{"name": "{{randomName}}", "code": "{{randomString 6}}", "age": "{{randomInt 18 99}}"},
+		"tags": []any{"{{randomString}}", "{{randomString}}"},
+	}
+
+	// the same seed generates the same values, whatever the order of the keys
+	run := func(seed int64) any {
+		SeedGenerators(seed)
+		return []any{Interpolate("/users/{{uuid}}", nil), Interpolate(body, nil)}
+	}
+	first, again := run(42), run(42)
+	if !reflect.DeepEqual(first, again) { t.Errorf("two runs seeded with 42 generated\n%v\nand\n%v", first, again) }
+	if other := run(7); reflect.DeepEqual(first, other) { t.Errorf("the seeds 42 and 7 generated the same values %v", other) }
+}
+
+func TestGenerators(t *testing.T) {
+	variables := map[string]any{"secret": "key", "user": map[string]any{"name": "ann"}}
+
+	// values that do not depend on the random source
+	for text, want := range map[string]any{
+		`{{base64 "ann:secret"}}`: "YW5uOnNlY3JldA==",
+		`{{base64 user.name}}`: "YW5u",
+		`{{hmac "sha256" secret "The quick brown fox jumps over the lazy dog"}}`: "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		`{{randomInt 5 5}}`: 5,
+		`{{now "2006"}}`: time.Now().Format("2006"),
+		`id {{randomInt 3 3}}`: "id 3",
+		// a generator that cannot run leaves its placeholder to be seen
+		`{{randomInt 5 1}}`: `{{randomInt 5 1}}`,
+		`{{hmac "md5" secret "x"}}`: `{{hmac "md5" secret "x"}}`,
+		`{{base64 missing}}`: `{{base64 missing}}`,
+	} {
+		if got := Interpolate(text, variables); got != want { t.Errorf("Interpolate(%s) = %#v, want %#v", text, got, want) }
+	}
+
+	patterns := map[string]*regexp.Regexp{
+		"{{uuid}}": regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		"{{randomEmail}}": regexp.MustCompile(`^user\.[a-z0-9]{8}@example\.com$`),
+		"{{randomString 20}}": regexp.MustCompile(`^[A-Za-z0-9]{20}$`),
+		"{{randomName}}": regexp.MustCompile(`^[A-Z][a-z]+ [A-Z][a-z]+$`),
+	}
+	for i := 0; i < 50; i++ {
+		for text, pattern := range patterns {
+			if got := Interpolate(text, nil).(string); !pattern.MatchString(got) { t.Fatalf("Interpolate(%s) = %s", text, got) }
+		}
+		if got := Interpolate("{{randomInt 1 3}}", nil).(int); got < 1 || got > 3 { t.Fatalf("randomInt 1 3 gave %d", got) }
+	}
+}
+
+func TestCheckGenerator(t *testing.T) {
+	for call, want := range map[string]string{
+		"uuid 1": "uuid takes 0 arguments, got 1",
+		"base64 0": "base64 takes 1 argument, got 0",
+		"now 2": "now takes 0 to 1 arguments, got 2",
+		"faker 0": `unknown function "faker"`,
+	} {
+		var name string
+		var count int
+		if _, err := fmt.Sscan(call, &name, &count); err != nil { t.Fatal(err) }
+		if err := CheckGenerator(name, count); err == nil || err.Error() != want { t.Errorf("CheckGenerator(%s, %d) = %v, want %s", name, count, err, want) }
+	}
+	if err := CheckGenerator("hmac", 3); err != nil { t.Errorf("CheckGenerator(hmac, 3) = %v", err) }
+}
@@ -0,0 +1,10 @@
+package cmd
+
+// Payload returns the body a GraphQL server expects for the operation,
+// with the variables interpolated
+func (g *GraphQL) Payload(variables map[string]any) map[string]any {
+	payload := map[string]any{"query": g.Query}
+	if len(g.Variables) > 0 { payload["variables"] = Interpolate(g.Variables, variables) }
+	if g.OperationName != "" { payload["operationName"] = g.OperationName }
+	return payload
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// HistoryFile keeps the timings of every run, next to the configuration
+const HistoryFile = "history.jsonl"
+
+// DefaultBaselineRuns is the number of earlier runs a baseline is made of
+const DefaultBaselineRuns = 5
+
+// DefaultBaselinePercentage is the slowdown flagged when no limit is configured
+const DefaultBaselinePercentage = 20
+
+// HistoryEntry is a single run of a step as kept in the history file, one
+// json object per line. Durations are in nanoseconds
+type HistoryEntry struct {
+	Run time.Time `json:"run"`
+	Environment string `json:"environment"`
+	Pipeline string `json:"pipeline"`
+	Iteration string `json:"iteration,omitempty"`
+	Step string `json:"step"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Passed bool `json:"passed"`
+	Duration time.Duration `json:"duration"`
+	DNS time.Duration `json:"dns,omitempty"`
+	Connect time.Duration `json:"connect,omitempty"`
+	TLS time.Duration `json:"tls,omitempty"`
+	TTFB time.Duration `json:"ttfb,omitempty"`
+	Transfer time.Duration `json:"transfer,omitempty"`
+	Total time.Duration `json:"total,omitempty"`
+}
+
+// Regression is a step slower than its baseline by more than the limits allow
+type Regression struct {
+	Entry HistoryEntry
+	Baseline time.Duration
+	Runs int
+}
+
+// HistoryEntries turns the results of a run into history entries, leaving
+// out the skipped steps. A step named like an earlier one of its pipeline,
+// such as the same endpoint hit twice, is numbered to tell them apart
+func HistoryEntries(results []StepResult, environment string, run time.Time) []HistoryEntry {
+	entries, seen := []HistoryEntry{}, map[string]int{}
+	for _, result := range results {
+		step := result.Step
+		key := result.Pipeline + "|" + result.Iteration + "|" + step
+		if seen[key]++; seen[key] > 1 { step = fmt.Sprintf("%s #%d", step, seen[key]) }
+		if result.Skipped { continue }
+
+		entries = append(entries, HistoryEntry{
+			Run: run,
+			Environment: environment,
+			Pipeline: result.Pipeline,
+			Iteration: result.Iteration,
+			Step: step,
+			Endpoint: result.Endpoint,
+			Passed: result.Err == nil,
+			Duration: result.Duration,
+			DNS: result.Timings.DNS,
+			Connect: result.Timings.Connect,
+			TLS: result.Timings.TLS,
+			TTFB: result.Timings.TTFB,
+			Transfer: result.Timings.Transfer,
+			Total: result.Timings.Total,
+		})
+	}
+	return entries
+}
+
+// Latency is the time a step is compared on: its HTTP request when it made
+// one, the whole step otherwise
+func (e HistoryEntry) Latency() time.Duration {
+	if e.Total > 0 { return e.Total }
+	return e.Duration
+}
+
+// Key tells apart the steps of the history: the same step of the same
+// pipeline and row, against the same environment
+func (e HistoryEntry) Key() string {
+	return e.Environment + "|" + e.Pipeline + "|" + e.Iteration + "|" + e.Step
+}
+
+// ReadHistory reads the entries of a history file in the order they were
+// written, a missing file has none
+func ReadHistory(path string) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) { return []HistoryEntry{}, nil }
+	if err != nil { return nil, fmt.Errorf("Could not read history file %s", path) }
+	defer file.Close()
+
+	entries := []HistoryEntry{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 { continue }
+
+		entry := HistoryEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil { return nil, fmt.Errorf("Could not parse history file %s at line %d: %s", path, line, err.Error()) }
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil { return nil, fmt.Errorf("Could not read history file %s", path) }
+	return entries, nil
+}
+
+// AppendHistory adds entries at the end of a history file, creating it if needed
+func AppendHistory(path string, entries []HistoryEntry) error {
+	file, err := os.OpenFile(path, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0644)
+	if err != nil { return fmt.Errorf("Could not write history file %s", path) }
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil { return fmt.Errorf("Could not write history file %s", path) }
+	}
+	return nil
+}
+
+// Regressions compares the passing steps of a run with their baseline, the
+// median of their last passing runs in the history. A step regressed when
+// it is slower than its baseline by more than every limit that is set, 20
+// percent when none is
+func Regressions(history []HistoryEntry, current []HistoryEntry, settings Baseline) ([]Regression, error) {
+	threshold := time.Duration(0)
+	if settings.Threshold != "" {
+		parsed, err := time.ParseDuration(settings.Threshold)
+		if err != nil { return nil, fmt.Errorf("Could not read baseline threshold %q: %v", settings.Threshold, err) }
+		threshold = parsed
+	}
+	percentage := settings.Percentage
+	if threshold == 0 && percentage == 0 { percentage = DefaultBaselinePercentage }
+	runs := settings.Runs
+	if runs == 0 { runs = DefaultBaselineRuns }
+
+	// the latencies of the passing runs of every step, oldest first
+	latencies := map[string][]time.Duration{}
+	for _, entry := range history {
+		if entry.Passed { latencies[entry.Key()] = append(latencies[entry.Key()], entry.Latency()) }
+	}
+
+	regressions := []Regression{}
+	for _, entry := range current {
+		earlier := latencies[entry.Key()]
+		if !entry.Passed || len(earlier) == 0 { continue }
+		if len(earlier) > runs { earlier = earlier[len(earlier) - runs:] }
+
+		baseline := Median(earlier)
+		slowdown := entry.Latency() - baseline
+		if threshold > 0 && slowdown <= threshold { continue }
+		if percentage > 0 && float64(slowdown) <= float64(baseline) * percentage / 100 { continue }
+		regressions = append(regressions, Regression{Entry: entry, Baseline: baseline, Runs: len(earlier)})
+	}
+	return regressions, nil
+}
+
+// Median is the middle one of some durations
+func Median(durations []time.Duration) time.Duration {
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	middle := len(sorted) / 2
+	if len(sorted) % 2 == 0 { return (sorted[middle - 1] + sorted[middle]) / 2 }
+	return sorted[middle]
+}
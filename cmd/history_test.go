@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryEntries(t *testing.T) {
+	run := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	results := []StepResult{
+		{Pipeline: "current", Step: "GET /users", Duration: 10 * time.Millisecond, Timings: Timings{Total: 8 * time.Millisecond}},
+		{Pipeline: "current", Step: "GET /users", Skipped: true},
+		{Pipeline: "current", Step: "GET /users", Err: errors.New("failed")},
+		{Pipeline: "current", Iteration: "row 1", Step: "GET /users"},
+		{Pipeline: "users", Step: "GET /users"},
+	}
+
+	entries := HistoryEntries(results, "staging", run)
+	steps := []string{}
+	for _, entry := range entries { steps = append(steps, entry.Pipeline + "|" + entry.Iteration + "|" + entry.Step) }
+	want := []string{"current||GET /users", "current||GET /users #3", "current|row 1|GET /users", "users||GET /users"}
+	if !reflect.DeepEqual(steps, want) { t.Errorf("HistoryEntries() made steps %q, want %q", steps, want) }
+
+	if first := entries[0]; !first.Passed || first.Environment != "staging" || !first.Run.Equal(run) || first.Latency() != 8 * time.Millisecond {
+		t.Errorf("HistoryEntries() made %+v", first)
+	}
+	if entries[1].Passed { t.Errorf("HistoryEntries() made the failed step pass") }
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFile)
+
+	entries, err := ReadHistory(path)
+	if err != nil || len(entries) != 0 { t.Fatalf("ReadHistory() of a missing file returned %v, %v", entries, err) }
+
+	run := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	first := []HistoryEntry{{Run: run, Environment: "development", Pipeline: "current", Step: "a", Passed: true, Duration: time.Second}}
+	second := []HistoryEntry{{Run: run.Add(time.Hour), Environment: "development", Pipeline: "current", Step: "a", Total: time.Millisecond}}
+	if err := AppendHistory(path, first); err != nil { t.Fatal(err) }
+	if err := AppendHistory(path, second); err != nil { t.Fatal(err) }
+
+	entries, err = ReadHistory(path)
+	if err != nil { t.Fatal(err) }
+	if want := append(first, second...); !reflect.DeepEqual(entries, want) { t.Errorf("ReadHistory() = %+v, want %+v", entries, want) }
+
+	if err := os.WriteFile(path, []byte("{\"step\": \"a\"}\n\n{broken\n"), 0644); err != nil { t.Fatal(err) }
+	if _, err := ReadHistory(path); err == nil || !strings.Contains(err.Error(), "at line 3") { t.Errorf("ReadHistory() returned %v, want an error at line 3", err) }
+}
+
+func TestRegressions(t *testing.T) {
+	// the step of the history failed, took 500ms once and then 100ms five times
+	history := []HistoryEntry{{Step: "a", Passed: false, Duration: time.Second}}
+	for _, duration := range []time.Duration{500, 100, 100, 100, 100, 100} {
+		history = append(history, HistoryEntry{Step: "a", Passed: true, Duration: duration * time.Millisecond})
+	}
+
+	tests := []struct {
+		name string
+		settings Baseline
+		entry HistoryEntry
+		want bool
+	}{
+		{name: "within the default percentage", entry: HistoryEntry{Step: "a", Passed: true, Duration: 120 * time.Millisecond}},
+		{name: "over the default percentage", entry: HistoryEntry{Step: "a", Passed: true, Duration: 121 * time.Millisecond}, want: true},
+		{name: "within the threshold", settings: Baseline{Threshold: "50ms"}, entry: HistoryEntry{Step: "a", Passed: true, Duration: 150 * time.Millisecond}},
+		{name: "over the threshold", settings: Baseline{Threshold: "50ms"}, entry: HistoryEntry{Step: "a", Passed: true, Duration: 151 * time.Millisecond}, want: true},
+		{name: "over the threshold only", settings: Baseline{Threshold: "10ms", Percentage: 50}, entry: HistoryEntry{Step: "a", Passed: true, Duration: 140 * time.Millisecond}},
+		{name: "over both", settings: Baseline{Threshold: "10ms", Percentage: 50}, entry: HistoryEntry{Step: "a", Passed: true, Duration: 160 * time.Millisecond}, want: true},
+		{name: "baseline of more runs", settings: Baseline{Runs: 6}, entry: HistoryEntry{Step: "a", Passed: true, Duration: 121 * time.Millisecond}, want: true},
+		{name: "failed step", entry: HistoryEntry{Step: "a", Duration: time.Second}},
+		{name: "new step", entry: HistoryEntry{Step: "b", Passed: true, Duration: time.Second}},
+		{name: "other environment", entry: HistoryEntry{Environment: "staging", Step: "a", Passed: true, Duration: time.Second}},
+		{name: "request latency", entry: HistoryEntry{Step: "a", Passed: true, Duration: time.Second, Total: 110 * time.Millisecond}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			regressions, err := Regressions(history, []HistoryEntry{test.entry}, test.settings)
+			if err != nil { t.Fatal(err) }
+			if got := len(regressions) == 1; got != test.want { t.Errorf("Regressions() = %+v, want a regression %v", regressions, test.want) }
+			runs := DefaultBaselineRuns
+			if test.settings.Runs != 0 { runs = test.settings.Runs }
+			if len(regressions) == 1 && (regressions[0].Baseline != 100 * time.Millisecond || regressions[0].Runs != runs) {
+				t.Errorf("Regressions() compared with %s over %d runs", regressions[0].Baseline, regressions[0].Runs)
+			}
+		})
+	}
+
+	if _, err := Regressions(history, history, Baseline{Threshold: "soon"}); err == nil { t.Errorf("Regressions() accepted the threshold soon") }
+}
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		durations []time.Duration
+		want time.Duration
+	}{
+		{[]time.Duration{3}, 3},
+		{[]time.Duration{5, 1, 3}, 3},
+		{[]time.Duration{4, 1, 3, 2}, 2},
+	}
+
+	for _, test := range tests {
+		if got := Median(test.durations); got != test.want { t.Errorf("Median(%v) = %v, want %v", test.durations, got, test.want) }
+	}
+}
@@ -1,31 +1,33 @@
 package json
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
-	"os"
-
 	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/config"
 )
 
-// Reader purpose is just to allow the programmer to make selection for JSON
-type Reader struct{}
+// Reader purpose is just to allow the programmer to make selection for JSON.
+// Relaxed readers accept comments and the other JSONC/JSON5 conveniences
+type Reader struct {
+	Relaxed bool
+}
 
 // ReadInstructions decodes and stores all the instructions from the configuration
-// file in the state of the program
-func (r* Reader) ReadInstructions(filepath string) (*cmd.Structure, error) {
-	
-	file, err := os.Open(filepath)
-	if err != nil { return &cmd.Structure{}, fmt.Errorf("Could not open file") }
+// file in the state of the program. Included files are merged in and the
+// result is validated first so mistakes are reported with their file, line
+// and column
+func (r *Reader) ReadInstructions(filepath string) (*cmd.Structure, error) {
+
+	parse := config.ParseJSON
+	if r.Relaxed { parse = config.ParseJSONC }
+
+	root, err := config.Load(filepath, parse)
+	if err != nil { return &cmd.Structure{}, err }
 
-	defer file.Close()
+	if err := config.Validate(root); err != nil { return &cmd.Structure{}, err }
 
-	fileRawContents, err := io.ReadAll(file)
-	if err != nil { return &cmd.Structure{}, fmt.Errorf("Could not read file contents") }
-	
 	fileContents := new(cmd.Structure)
-	json.Unmarshal(fileRawContents, &fileContents)
+	if err := config.Decode(root, fileContents); err != nil { return &cmd.Structure{}, err }
+	config.Locate(root, fileContents)
 
-	return fileContents, nil;
+	return fileContents, nil
 }
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalJSON reads a pipeline written as a bare list of steps as well
+func (p *Pipeline) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, &p.Steps)
+	}
+
+	type plain Pipeline
+	return json.Unmarshal(data, (*plain)(p))
+}
+
+// MarshalJSON writes a pipeline without data nor hooks as a bare list of steps
+func (p Pipeline) MarshalJSON() ([]byte, error) {
+	if p.bare() { return json.Marshal(p.Steps) }
+
+	type plain Pipeline
+	return json.Marshal(plain(p))
+}
+
+// MarshalYAML writes a pipeline without data nor hooks as a bare list of steps
+func (p Pipeline) MarshalYAML() (any, error) {
+	if p.bare() { return p.Steps, nil }
+
+	type plain Pipeline
+	return plain(p), nil
+}
+
+// bare tells if a pipeline only has steps
+func (p Pipeline) bare() bool {
+	return p.Data == "" && len(p.BeforeAll) + len(p.AfterAll) + len(p.BeforeEach) + len(p.AfterEach) == 0
+}
+
+// ReadRows reads the rows of a data file: a csv file whose first line names
+// the columns, or a json or yaml array of objects
+func ReadRows(path string) ([]map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil { return nil, fmt.Errorf("Could not read data file %s", path) }
+
+	rows := []map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil { return nil, fmt.Errorf("Could not parse data file %s: %s", path, err.Error()) }
+		if len(records) == 0 { return rows, nil }
+
+		// csv values are strings, json and yaml keep their types
+		for _, record := range records[1:] {
+			row := map[string]any{}
+			for i, column := range records[0] { row[strings.TrimSpace(column)] = record[i] }
+			rows = append(rows, row)
+		}
+	case ".json":
+		if err := json.Unmarshal(data, &rows); err != nil { return nil, fmt.Errorf("Could not parse data file %s: %s", path, err.Error()) }
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &rows); err != nil { return nil, fmt.Errorf("Could not parse data file %s: %s", path, err.Error()) }
+	default:
+		return nil, fmt.Errorf("Invalid data file %s, use csv, json, yaml or yml", path)
+	}
+
+	return rows, nil
+}
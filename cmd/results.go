@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+)
+
+// StepResult is the outcome of a single run of a step. A step looping over
+// items or sitting in a pipeline driven by a data file has one per run
+type StepResult struct {
+	Pipeline string
+	Iteration string
+	Step string
+	Endpoint string
+	Skipped bool
+	Err error
+	Duration time.Duration
+	Timings Timings
+}
+
+// Timings are the phases of the HTTP requests of a step. A step redirected
+// along the way adds up the phases of every request, transfer being the
+// reading of the last response
+type Timings struct {
+	DNS time.Duration
+	Connect time.Duration
+	TLS time.Duration
+	TTFB time.Duration
+	Transfer time.Duration
+	Total time.Duration
+}
+
+// Measured tells if the timings come from an HTTP request, other kinds of
+// steps have none
+func (t Timings) Measured() bool {
+	return t.Total > 0
+}
+
+// String lists the phases in the order they happen
+func (t Timings) String() string {
+	return fmt.Sprintf("dns %s, connect %s, tls %s, ttfb %s, transfer %s, total %s", t.DNS, t.Connect, t.TLS, t.TTFB, t.Transfer, t.Total)
+}
+
+// Values names the phases the way expressions read them, such as `duration.ttfb`
+func (t Timings) Values() map[string]any {
+	return map[string]any{"dns": t.DNS, "connect": t.Connect, "tls": t.TLS, "ttfb": t.TTFB, "transfer": t.Transfer, "total": t.Total}
+}
+
+// Record keeps the outcome of a step for the summary and the reports, along
+// with the pipeline and the row it ran for. Steps of hooks are named after
+// their hook
+func (s *Structure) Record(result StepResult) {
+	if s.ActiveHook != "" { result.Step = s.ActiveHook + " " + result.Step }
+	result.Pipeline, result.Iteration = s.ActivePipeline, s.ActiveIteration
+	s.Results = append(s.Results, result)
+}
+
+// Failures counts the recorded steps that failed
+func (s *Structure) Failures() int {
+	failures := 0
+	for _, result := range s.Results {
+		if result.Err != nil { failures++ }
+	}
+	return failures
+}
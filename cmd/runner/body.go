@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// requestBody encodes the body of a step according to its body type and
+// returns it along with the content type it has to be sent with. No body
+// is sent for `none` or when the step has no body at all. Files are read
+// relative to dir, the directory of the file declaring the body
+func requestBody(dir string, bodyType string, body any) (io.Reader, string, error) {
+	if bodyType == "" { bodyType = "json" }
+	if bodyType == "none" || body == nil { return nil, "", nil }
+
+	switch bodyType {
+	case "json":
+		data, err := json.Marshal(body)
+		if err != nil { return nil, "", err }
+		return bytes.NewReader(data), "application/json", nil
+
+	case "form":
+		fields, ok := body.(map[string]any)
+		if !ok { return nil, "", fmt.Errorf("Could not encode form body, it has to be an object") }
+
+		values := url.Values{}
+		for _, key := range sortedKeys(fields) {
+			for _, value := range listOf(fields[key]) { values.Add(key, fmt.Sprint(value)) }
+		}
+		return strings.NewReader(values.Encode()), "application/x-www-form-urlencoded", nil
+
+	case "multipart":
+		fields, ok := body.(map[string]any)
+		if !ok { return nil, "", fmt.Errorf("Could not encode multipart body, it has to be an object") }
+		return multipartBody(dir, fields)
+
+	case "raw":
+		if text, ok := body.(string); ok { return strings.NewReader(text), "text/plain", nil }
+		path, ok := cmd.FileReference(body)
+		if !ok { return nil, "", fmt.Errorf("Could not send raw body, it has to be a string or {file: path}") }
+
+		data, err := readBodyFile(dir, path)
+		if err != nil { return nil, "", err }
+		return bytes.NewReader(data), contentTypeOf(path, "text/plain"), nil
+
+	case "binary":
+		path, ok := cmd.FileReference(body)
+		if !ok { return nil, "", fmt.Errorf("Could not send binary body, it has to be {file: path}") }
+
+		data, err := readBodyFile(dir, path)
+		if err != nil { return nil, "", err }
+		return bytes.NewReader(data), "application/octet-stream", nil
+	}
+
+	return nil, "", fmt.Errorf("Unknown bodyType %q", bodyType)
+}
+
+// multipartBody writes every field as a form-data part, `{file: path}`
+// values are uploaded as files and lists repeat the field
+func multipartBody(dir string, fields map[string]any) (io.Reader, string, error) {
+	buffer := &bytes.Buffer{}
+	writer := multipart.NewWriter(buffer)
+
+	for _, key := range sortedKeys(fields) {
+		for _, value := range listOf(fields[key]) {
+			if _, isFile := cmd.FileReference(value); isFile {
+				if err := filePart(writer, dir, key, value.(map[string]any)); err != nil { return nil, "", err }
+				continue
+			}
+			if err := writer.WriteField(key, fmt.Sprint(value)); err != nil { return nil, "", err }
+		}
+	}
+
+	if err := writer.Close(); err != nil { return nil, "", err }
+	return buffer, writer.FormDataContentType(), nil
+}
+
+// filePart uploads a single file, `filename` and `contentType` may be
+// given next to `file` to override what is guessed from the path
+func filePart(writer *multipart.Writer, dir string, name string, part map[string]any) error {
+	path := fmt.Sprint(part["file"])
+	data, err := readBodyFile(dir, path)
+	if err != nil { return err }
+
+	filename := filepath.Base(path)
+	if value, ok := part["filename"].(string); ok { filename = value }
+
+	contentType := contentTypeOf(path, "application/octet-stream")
+	if value, ok := part["contentType"].(string); ok { contentType = value }
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(name), quoteEscaper.Replace(filename)))
+	header.Set("Content-Type", contentType)
+
+	destination, err := writer.CreatePart(header)
+	if err != nil { return err }
+
+	_, err = destination.Write(data)
+	return err
+}
+
+// quoteEscaper escapes the names written inside Content-Disposition
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// readBodyFile reads a file of a body, relative paths start at dir
+func readBodyFile(dir string, path string) ([]byte, error) {
+	if !filepath.IsAbs(path) { path = filepath.Join(dir, path) }
+
+	data, err := os.ReadFile(path)
+	if err != nil { return nil, fmt.Errorf("Could not read body file %q", path) }
+	return data, nil
+}
+
+// contentTypeOf guesses the content type of a file from its extension
+func contentTypeOf(path string, fallback string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" { return contentType }
+	return fallback
+}
+
+// listOf turns a single value into a list so lists and values are handled alike
+func listOf(value any) []any {
+	if list, ok := value.([]any); ok { return list }
+	if value == nil { return nil }
+	return []any{value}
+}
+
+// sortedKeys returns the keys of an object in a stable order
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object { keys = append(keys, key) }
+	sort.Strings(keys)
+	return keys
+}
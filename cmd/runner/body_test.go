@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// bodyDir holds the files the bodies of the tests upload
+func bodyDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range map[string]string{"note.txt": "hello", "data.bin": "\x00\x01", "avatar.png": "png", "query.json": `{"q":1}`} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil { t.Fatal(err) }
+	}
+	return dir
+}
+
+func TestRequestBody(t *testing.T) {
+	dir := bodyDir(t)
+
+	tests := []struct {
+		bodyType string
+		body any
+		contentType string
+		want string
+	}{
+		{"", map[string]any{"name": "Jane", "age": float64(30)}, "application/json", `{"age":30,"name":"Jane"}`},
+		{"json", []any{"a", nil}, "application/json", `["a",null]`},
+		{"form", map[string]any{"scope": []any{"read", "write"}, "user": "jane doe"}, "application/x-www-form-urlencoded", "scope=read&scope=write&user=jane+doe"},
+		{"raw", "plain text", "text/plain", "plain text"},
+		{"raw", map[string]any{"file": "query.json"}, "application/json", `{"q":1}`},
+		{"binary", map[string]any{"file": filepath.Join(dir, "data.bin")}, "application/octet-stream", "\x00\x01"},
+		{"none", map[string]any{"ignored": true}, "", ""},
+		{"json", nil, "", ""},
+	}
+
+	for _, test := range tests {
+		reader, contentType, err := requestBody(dir, test.bodyType, test.body)
+		if err != nil { t.Errorf("requestBody(%q, %v) returned %v", test.bodyType, test.body, err); continue }
+
+		got := ""
+		if reader != nil {
+			data, _ := io.ReadAll(reader)
+			got = string(data)
+		}
+		if got != test.want || contentType != test.contentType { t.Errorf("requestBody(%q, %v) = %q as %q, want %q as %q", test.bodyType, test.body, got, contentType, test.want, test.contentType) }
+	}
+}
+
+func TestRequestBodyMultipart(t *testing.T) {
+	body := map[string]any{
+		"alt": "me",
+		"avatar": map[string]any{"file": "avatar.png"},
+		"docs": []any{map[string]any{"file": "note.txt", "filename": "readme", "contentType": "text/markdown"}, "inline"},
+	}
+	reader, contentType, err := requestBody(bodyDir(t), "multipart", body)
+	if err != nil { t.Fatal(err) }
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil { t.Fatalf("multipart content type %q: %v", contentType, err) }
+
+	// parts come in key order and a list repeats its field
+	want := [][4]string{
+		{"alt", "", "", "me"},
+		{"avatar", "avatar.png", "image/png", "png"},
+		{"docs", "readme", "text/markdown", "hello"},
+		{"docs", "", "", "inline"},
+	}
+	parts := multipart.NewReader(reader, params["boundary"])
+	for _, expected := range want {
+		part, err := parts.NextPart()
+		if err != nil { t.Fatalf("multipart body ended before %s: %v", expected[0], err) }
+
+		data, _ := io.ReadAll(part)
+		got := [4]string{part.FormName(), part.FileName(), part.Header.Get("Content-Type"), string(data)}
+		if got != expected { t.Errorf("multipart part = %q, want %q", got, expected) }
+	}
+	if _, err := parts.NextPart(); err != io.EOF { t.Errorf("multipart body has parts left: %v", err) }
+}
+
+func TestRequestBodyErrors(t *testing.T) {
+	dir := bodyDir(t)
+
+	for bodyType, body := range map[string]any{
+		"form": "a=1",
+		"multipart": []any{"a"},
+		"raw": float64(1),
+		"binary": "data.bin",
+		"xml": "<a/>",
+	} {
+		if _, _, err := requestBody(dir, bodyType, body); err == nil { t.Errorf("requestBody(%q, %v) encoded a body it cannot send", bodyType, body) }
+	}
+
+	// a file is looked for in the directory of the file declaring it
+	if _, _, err := requestBody(t.TempDir(), "binary", map[string]any{"file": "data.bin"}); err == nil { t.Errorf("requestBody() read data.bin outside of its directory") }
+}
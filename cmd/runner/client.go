@@ -0,0 +1,223 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/proxy"
+)
+
+// MaxRedirects is the number of redirects a request follows before failing
+const MaxRedirects = 10
+
+// dialer opens the connections of the run
+var dialer = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+// httpClient is the client requests are sent with, holding the transport
+// and the cookie jar of the run
+func httpClient(fileContents *cmd.Structure) (*http.Client, error) {
+	transport, err := runTransport(fileContents)
+	if err != nil { return nil, err }
+
+	client := &http.Client{Transport: transport}
+	if fileContents.Jar != nil { client.Jar = fileContents.Jar }
+	return client, nil
+}
+
+// redirectPolicy follows redirects unless told otherwise, listing the urls
+// the request was sent to along the way
+func redirectPolicy(follow *bool, redirects *[]string) func(*http.Request, []*http.Request) error {
+	return func(next *http.Request, via []*http.Request) error {
+		if follow != nil && !*follow { return http.ErrUseLastResponse }
+		if len(via) >= MaxRedirects { return fmt.Errorf("stopped after %d redirects", MaxRedirects) }
+
+		*redirects = append(*redirects, next.URL.String())
+		return nil
+	}
+}
+
+// runTransport makes the transport of the run on first use, out of the TLS
+// and transport settings of the active environment, so connections are
+// reused across steps
+func runTransport(fileContents *cmd.Structure) (http.RoundTripper, error) {
+	if fileContents.Transport != nil { return fileContents.Transport, nil }
+
+	var config *tls.Config
+	settings := fileContents.ActiveTLS()
+	if !settings.Empty() {
+		var err error
+		config, err = settings.Config(fileContents.BaseDir(settings.Dir))
+		if err != nil { return nil, err }
+	}
+
+	if settings.InsecureSkipVerify {
+		fmt.Println(utils.Red + "\nWARNING: TLS certificates are not verified in " + fileContents.ActiveEnvironment + ", any server can pretend to be " + fileContents.ActiveURL + "\n" + utils.Reset)
+	}
+
+	options := fileContents.ActiveTransport()
+	proxyOf, err := runProxy(fileContents)
+	if err != nil { return nil, err }
+
+	// HTTP/2 is forced through its own transport, over plain http as well,
+	// tunnelling through the proxy when there is one. Dialing by itself, it
+	// reports the connect and TLS phases to the trace of the request
+	if options.Protocol == "http2" {
+		secure, scheme := strings.HasPrefix(fileContents.ActiveURL, "https"), "http"
+		if secure { scheme = "https" }
+		transport := &http2.Transport{
+			TLSClientConfig: config,
+			AllowHTTP: !secure,
+			DisableCompression: options.DisableCompression,
+			DialTLSContext: func(ctx context.Context, network string, address string, config *tls.Config) (net.Conn, error) {
+				trace := httptrace.ContextClientTrace(ctx)
+				if trace == nil { trace = &httptrace.ClientTrace{} }
+
+				if trace.ConnectStart != nil { trace.ConnectStart("tcp", address) }
+				conn, err := connect(ctx, fileContents, proxyOf, scheme, address)
+				if trace.ConnectDone != nil { trace.ConnectDone("tcp", address, err) }
+				if err != nil || !secure { return conn, err }
+
+				if trace.TLSHandshakeStart != nil { trace.TLSHandshakeStart() }
+				tlsConn := tls.Client(conn, config)
+				err = tlsConn.HandshakeContext(ctx)
+				if trace.TLSHandshakeDone != nil { trace.TLSHandshakeDone(tlsConn.ConnectionState(), err) }
+				if err != nil { conn.Close(); return nil, err }
+				return tlsConn, nil
+			},
+		}
+
+		fileContents.Transport = transport
+		if options.DisableKeepAlives { fileContents.Transport = singleUse{transport} }
+		return fileContents.Transport, nil
+	}
+
+	// a unix socket takes every connection, whatever the host of the url
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig, transport.Proxy = config, proxyOf
+	transport.DisableKeepAlives, transport.DisableCompression = options.DisableKeepAlives, options.DisableCompression
+	if socket := fileContents.ActiveSocket; socket != "" {
+		transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) { return dialer.DialContext(ctx, "unix", socket) }
+	}
+
+	// an empty TLSNextProto keeps HTTP/2 from being negotiated
+	if options.Protocol == "http1.1" {
+		transport.ForceAttemptHTTP2, transport.TLSNextProto = false, map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	fileContents.Transport = transport
+	return transport, nil
+}
+
+// runProxy picks the proxy of every connection: the one of the environment,
+// which wins over the HTTP_PROXY variables read otherwise
+func runProxy(fileContents *cmd.Structure) (func(*http.Request) (*url.URL, error), error) {
+	address := fileContents.ActiveTransport().Proxy
+	if address == "" { return http.ProxyFromEnvironment, nil }
+
+	proxy, err := proxyURL(fmt.Sprint(cmd.Interpolate(address, fileContents.Variables)))
+	if err != nil { return nil, err }
+	return http.ProxyURL(proxy), nil
+}
+
+// connect opens a connection to a host and port the way the requests of the
+// run reach it, for the clients that dial by themselves such as forced
+// HTTP/2 and websockets: through the unix socket of the environment when it
+// has one, else through the proxy when there is one
+func connect(ctx context.Context, fileContents *cmd.Structure, proxyOf func(*http.Request) (*url.URL, error), scheme string, address string) (net.Conn, error) {
+	if socket := fileContents.ActiveSocket; socket != "" { return dialer.DialContext(ctx, "unix", socket) }
+
+	proxy, err := proxyOf(&http.Request{URL: &url.URL{Scheme: scheme, Host: address}})
+	if err != nil { return nil, err }
+	if proxy == nil { return dialer.DialContext(ctx, "tcp", address) }
+	return tunnel(ctx, proxy, address)
+}
+
+// tunnel opens a connection to an address through a proxy: a socks5 one,
+// or an http or https one asked to CONNECT
+func tunnel(ctx context.Context, proxyAddress *url.URL, address string) (net.Conn, error) {
+	if strings.HasPrefix(proxyAddress.Scheme, "socks5") {
+		socks, err := proxy.FromURL(proxyAddress, dialer)
+		if err != nil { return nil, err }
+		return socks.(proxy.ContextDialer).DialContext(ctx, "tcp", address)
+	}
+
+	host := proxyAddress.Host
+	if proxyAddress.Port() == "" && proxyAddress.Scheme == "https" { host = net.JoinHostPort(proxyAddress.Hostname(), "443") }
+	if proxyAddress.Port() == "" && proxyAddress.Scheme == "http" { host = net.JoinHostPort(proxyAddress.Hostname(), "80") }
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil { return nil, err }
+	if proxyAddress.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyAddress.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil { conn.Close(); return nil, err }
+		conn = tlsConn
+	}
+
+	// the proxy has until the step times out to open the tunnel
+	if deadline, ok := ctx.Deadline(); ok { conn.SetDeadline(deadline) }
+	defer conn.SetDeadline(time.Time{})
+
+	req := &http.Request{Method: "CONNECT", URL: &url.URL{Opaque: address}, Host: address, Header: http.Header{}}
+	if user := proxyAddress.User; user != nil {
+		password, _ := user.Password()
+		req.Header.Set("Proxy-Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password)))
+	}
+	if err := req.Write(conn); err != nil { conn.Close(); return nil, err }
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil { conn.Close(); return nil, err }
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK { conn.Close(); return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", proxyAddress.Redacted(), address, res.Status) }
+	return conn, nil
+}
+
+// proxyURL reads the url of a proxy, credentials included
+func proxyURL(address string) (*url.URL, error) {
+	proxy, err := url.Parse(address)
+	if err != nil || proxy.Host == "" { return nil, fmt.Errorf("Invalid proxy %s, use a url such as http://host:port", address) }
+
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return proxy, nil
+	}
+	return nil, fmt.Errorf("Invalid proxy %s, use http, https or socks5", proxy.Redacted())
+}
+
+// tlsConfig is the TLS configuration of the run, for the clients that do not
+// go through httpClient such as gRPC and websockets. nil means the defaults
+func tlsConfig(fileContents *cmd.Structure) (*tls.Config, error) {
+	transport, err := runTransport(fileContents)
+	if err != nil { return nil, err }
+
+	switch t := transport.(type) {
+	case *http.Transport: return t.TLSClientConfig, nil
+	case *http2.Transport: return t.TLSClientConfig, nil
+	case singleUse: return t.TLSClientConfig, nil
+	}
+	return nil, nil
+}
+
+// singleUse gives every request of the forced HTTP/2 transport a connection
+// of its own, closed once the response is read, as it has no
+// DisableKeepAlives to set
+type singleUse struct {
+	*http2.Transport
+}
+
+// RoundTrip sends a copy of the request asking for its connection to be closed
+func (s singleUse) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Close = true
+	return s.Transport.RoundTrip(req)
+}
@@ -0,0 +1,305 @@
+package runner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/net/websocket"
+)
+
+// writePEM writes a single PEM block to a file of dir
+func writePEM(t *testing.T, dir string, name string, kind string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: data}), 0600); err != nil { t.Fatal(err) }
+}
+
+// clientCertificate writes a self-signed client certificate and its key to
+// client.pem and client-key.pem, returning the certificate
+func clientCertificate(t *testing.T, dir string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil { t.Fatal(err) }
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{CommonName: "apee-i"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter: time.Now().Add(time.Hour),
+		KeyUsage: x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	data, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil { t.Fatal(err) }
+	keyData, err := x509.MarshalECPrivateKey(key)
+	if err != nil { t.Fatal(err) }
+
+	writePEM(t, dir, "client.pem", "CERTIFICATE", data)
+	writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", keyData)
+	certificate, err := x509.ParseCertificate(data)
+	if err != nil { t.Fatal(err) }
+	return certificate
+}
+
+// tlsStructure is a configuration whose development environment is the server
+func tlsStructure(t *testing.T, dir string, url string, settings cmd.TLS) *cmd.Structure {
+	t.Helper()
+	fileContents := &cmd.Structure{
+		BaseURL: cmd.Environments{Development: url},
+		Environments: cmd.EnvironmentSettings{Development: cmd.Settings{TLS: settings}},
+		Variables: map[string]any{},
+		ConfigDir: dir,
+	}
+	if err := fileContents.SelectEnvironment("development"); err != nil { t.Fatal(err) }
+	return fileContents
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	certificate := clientCertificate(t, dir)
+
+	// the server only answers clients presenting the certificate
+	clients := x509.NewCertPool()
+	clients.AddCert(certificate)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"client": "` + r.TLS.PeerCertificates[0].Subject.CommonName + `"}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clients}
+	server.StartTLS()
+	defer server.Close()
+	writePEM(t, dir, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	tests := []struct {
+		name string
+		settings cmd.TLS
+		want string
+	}{
+		{name: "system authorities", settings: cmd.TLS{Cert: "client.pem", Key: "client-key.pem"}, want: "certificate signed by unknown authority"},
+		{name: "custom CA", settings: cmd.TLS{CA: "ca.pem", Cert: "client.pem", Key: "client-key.pem"}},
+		{name: "custom CA and server name", settings: cmd.TLS{CA: "ca.pem", Cert: "client.pem", Key: "client-key.pem", ServerName: "example.com"}},
+		{name: "wrong server name", settings: cmd.TLS{CA: "ca.pem", Cert: "client.pem", Key: "client-key.pem", ServerName: "other.org"}, want: "not other.org"},
+		{name: "no client certificate", settings: cmd.TLS{CA: "ca.pem"}, want: "certificate required"},
+		{name: "insecure", settings: cmd.TLS{InsecureSkipVerify: true, Cert: "client.pem", Key: "client-key.pem"}},
+		{name: "TLS1.3", settings: cmd.TLS{CA: "ca.pem", Cert: "client.pem", Key: "client-key.pem", MinVersion: "TLS1.3"}},
+		{name: "cert without key", settings: cmd.TLS{CA: "ca.pem", Cert: "client.pem"}, want: "Client certificate needs both cert and key"},
+		{name: "missing CA", settings: cmd.TLS{CA: "missing.pem"}, want: "Could not read CA bundle missing.pem"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := Hit(tlsStructure(t, dir, server.URL, test.settings), cmd.APIStructure{Endpoint: "/"})
+			if test.want != "" {
+				if err == nil || !strings.Contains(err.Error(), test.want) { t.Errorf("Hit() returned %v, want an error about %q", err, test.want) }
+				return
+			}
+			if err != nil { t.Fatalf("Hit() returned %v", err) }
+			if client := res.Body.Path("client").Data(); client != "apee-i" { t.Errorf("server saw client %v, want apee-i", client) }
+		})
+	}
+}
+
+func TestTLSDir(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{}`)) }))
+	defer server.Close()
+
+	// an included file in another directory declares the bundle
+	included := t.TempDir()
+	writePEM(t, included, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	fileContents := tlsStructure(t, t.TempDir(), server.URL, cmd.TLS{CA: "ca.pem", Dir: included})
+	if _, err := Hit(fileContents, cmd.APIStructure{Endpoint: "/"}); err != nil { t.Errorf("Hit() did not read the CA next to the file declaring it: %v", err) }
+
+	// without a directory of their own the files are read next to the configuration
+	fileContents = tlsStructure(t, included, server.URL, cmd.TLS{CA: "ca.pem"})
+	if _, err := Hit(fileContents, cmd.APIStructure{Endpoint: "/"}); err != nil { t.Errorf("Hit() did not read the CA next to the configuration: %v", err) }
+}
+
+func TestTLSHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"proto": "` + r.Proto + `"}`))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	writePEM(t, dir, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	tests := []struct {
+		protocol string
+		want string
+	}{
+		{"", "HTTP/2.0"},
+		{"http2", "HTTP/2.0"},
+		{"http1.1", "HTTP/1.1"},
+	}
+
+	for _, test := range tests {
+		fileContents := tlsStructure(t, dir, server.URL, cmd.TLS{CA: "ca.pem"})
+		fileContents.Environments.Development.Transport.Protocol = test.protocol
+
+		res, err := Hit(fileContents, cmd.APIStructure{Endpoint: "/"})
+		if err != nil { t.Errorf("Hit() with protocol %q returned %v", test.protocol, err); continue }
+		if proto := res.Body.Path("proto").Data(); proto != test.want { t.Errorf("Hit() with protocol %q went over %v, want %s", test.protocol, proto, test.want) }
+	}
+}
+
+// connectProxy is an http proxy tunnelling CONNECT requests of bob:secret,
+// counting the tunnels it opened
+func connectProxy(t *testing.T, tunnels *atomic.Int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "CONNECT" { http.Error(w, "only CONNECT is proxied", http.StatusMethodNotAllowed); return }
+		if r.Header.Get("Proxy-Authorization") != "Basic " + base64.StdEncoding.EncodeToString([]byte("bob:secret")) {
+			w.WriteHeader(http.StatusProxyAuthRequired); return
+		}
+
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil { http.Error(w, err.Error(), http.StatusBadGateway); return }
+		client, _, err := w.(http.Hijacker).Hijack()
+		if err != nil { target.Close(); return }
+		tunnels.Add(1)
+
+		client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() { io.Copy(target, client); target.Close() }()
+		io.Copy(client, target)
+		client.Close()
+	}))
+}
+
+func TestForcedHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	connections := &atomic.Int32{}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"proto": "` + r.Proto + `"}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew { connections.Add(1) }
+	}
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	writePEM(t, dir, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	// every request dials and shakes hands again once keep-alives are off,
+	// and the dial of the forced transport is timed like any other
+	for _, disabled := range []bool{false, true} {
+		fileContents := tlsStructure(t, dir, server.URL, cmd.TLS{CA: "ca.pem"})
+		fileContents.Environments.Development.Transport = cmd.TransportSettings{Protocol: "http2", DisableKeepAlives: disabled}
+		before := connections.Load()
+
+		for i := 0; i < 3; i++ {
+			res, err := Hit(fileContents, cmd.APIStructure{Endpoint: "/"})
+			if err != nil { t.Fatalf("Hit() with keep-alives disabled %v returned %v", disabled, err) }
+			if proto := res.Body.Path("proto").Data(); proto != "HTTP/2.0" { t.Errorf("request went over %v, want HTTP/2.0", proto) }
+
+			dialed := i == 0 || disabled
+			if timed := res.Timings.Connect > 0 && res.Timings.TLS > 0; timed != dialed { t.Errorf("request %d with keep-alives disabled %v timed connect %s and tls %s", i + 1, disabled, res.Timings.Connect, res.Timings.TLS) }
+		}
+
+		want := int32(1)
+		if disabled { want = 3 }
+		if got := connections.Load() - before; got != want { t.Errorf("3 requests with keep-alives disabled %v opened %d connections, want %d", disabled, got, want) }
+	}
+}
+
+func TestProxy(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/proto", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{"proto": "` + r.Proto + `"}`)) })
+	mux.Handle("/ws", websocket.Handler(func(conn *websocket.Conn) { io.Copy(conn, conn) }))
+	server := httptest.NewServer(h2c.NewHandler(mux, &http2.Server{}))
+	defer server.Close()
+
+	tunnels := &atomic.Int32{}
+	proxy := connectProxy(t, tunnels)
+	defer proxy.Close()
+	proxyAddress := strings.Replace(proxy.URL, "http://", "http://bob:{{password}}@", 1)
+
+	tests := []struct {
+		name string
+		password string
+		kind string
+		want string
+	}{
+		{name: "forced HTTP/2", password: "secret", want: "HTTP/2.0"},
+		{name: "websocket", password: "secret", kind: "websocket"},
+		{name: "wrong password", password: "wrong", want: "refused to connect"},
+		{name: "wrong password of a websocket", password: "wrong", kind: "websocket", want: "407 Proxy Authentication Required"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileContents := tlsStructure(t, t.TempDir(), server.URL, cmd.TLS{})
+			fileContents.Environments.Development.Transport = cmd.TransportSettings{Proxy: proxyAddress, Protocol: "http2"}
+			fileContents.Variables["password"] = test.password
+			before := tunnels.Load()
+
+			var res cmd.APIResponse
+			var err error
+			if test.kind == "websocket" {
+				step := cmd.PipelineBody{Kind: "websocket", Stream: &cmd.Stream{Send: []any{"ping"}, Expect: []cmd.StreamExpectation{{Match: "ping"}}}}
+				res, err = Listen(fileContents, step, cmd.APIStructure{Endpoint: "/ws", Timeout: "5s"})
+			} else {
+				res, err = Hit(fileContents, cmd.APIStructure{Endpoint: "/proto", Timeout: "5s"})
+			}
+
+			if test.password != "secret" {
+				if err == nil || !strings.Contains(err.Error(), test.want) { t.Errorf("returned %v, want an error about %q", err, test.want) }
+				if strings.Contains(fmt.Sprint(err), "wrong") { t.Errorf("returned %v, which shows the password of the proxy", err) }
+				return
+			}
+			if err != nil { t.Fatalf("returned %v", err) }
+			if tunnels.Load() == before { t.Errorf("the request did not go through the proxy") }
+			if test.want != "" {
+				if proto := res.Body.Path("proto").Data(); proto != test.want { t.Errorf("request went over %v, want %s", proto, test.want) }
+			}
+		})
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "apee-i")
+	if err != nil { t.Fatal(err) }
+	defer os.RemoveAll(dir)
+
+	// socket paths are short, so the socket does not sit in the test directory
+	listener, err := net.Listen("unix", filepath.Join(dir, "api.sock"))
+	if err != nil { t.Skipf("unix sockets are not available: %v", err) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/users", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{"path": "` + r.URL.Path + `"}`)) })
+	mux.Handle("/v1/ws", websocket.Handler(func(conn *websocket.Conn) { io.Copy(conn, conn) }))
+	server := &http.Server{Handler: h2c.NewHandler(mux, &http2.Server{})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	for _, protocol := range []string{"", "http2"} {
+		fileContents := tlsStructure(t, dir, "unix://" + filepath.Join(dir, "api.sock") + "/v1", cmd.TLS{})
+		fileContents.Environments.Development.Transport.Protocol = protocol
+
+		res, err := Hit(fileContents, cmd.APIStructure{Endpoint: "/users", Timeout: "5s"})
+		if err != nil { t.Errorf("Hit() with protocol %q returned %v", protocol, err); continue }
+		if path := res.Body.Path("path").Data(); path != "/v1/users" { t.Errorf("Hit() with protocol %q reached %v, want /v1/users", protocol, path) }
+
+		step := cmd.PipelineBody{Kind: "websocket", Stream: &cmd.Stream{Send: []any{"ping"}, Expect: []cmd.StreamExpectation{{Match: "ping"}}}}
+		if _, err := Listen(fileContents, step, cmd.APIStructure{Endpoint: "/ws", Timeout: "5s"}); err != nil { t.Errorf("Listen() with protocol %q returned %v", protocol, err) }
+	}
+}
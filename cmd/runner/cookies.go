@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// CookieFile is where the cookie jars of every environment are kept between
+// runs, next to the configuration
+const CookieFile = "cookies.json"
+
+// OpenCookieJar gives the run its cookie jar, read back from the jar of its
+// environment when the cookies persist. A scope of none leaves the run without one
+func OpenCookieJar(fileContents *cmd.Structure) error {
+	if fileContents.Cookies.Scope == "none" { fileContents.Jar = nil; return nil }
+	if !fileContents.Cookies.Persist { fileContents.Jar = cmd.NewCookieJar(); return nil }
+
+	jar, err := cmd.ReadCookieJar(cookiePath(fileContents), fileContents.ActiveEnvironment)
+	if err != nil { return err }
+	fileContents.Jar = jar
+	return nil
+}
+
+// SaveCookieJar stores the cookies of the run under its environment when they persist
+func SaveCookieJar(fileContents *cmd.Structure) error {
+	if !fileContents.Cookies.Persist || fileContents.Jar == nil { return nil }
+	return fileContents.Jar.Save(cookiePath(fileContents), fileContents.ActiveEnvironment)
+}
+
+// cookiePath is the CookieFile of the configuration
+func cookiePath(fileContents *cmd.Structure) string {
+	return filepath.Join(fileContents.ConfigDir, CookieFile)
+}
+
+// pipelineJar gives a pipeline a copy of the jar of the run when every
+// pipeline keeps its own cookies, the returned function puts the jar of the
+// run back
+func pipelineJar(fileContents *cmd.Structure) func() {
+	jar := fileContents.Jar
+	if fileContents.Cookies.Scope != "pipeline" || jar == nil { return func() {} }
+
+	fileContents.Jar = jar.Copy()
+	return func() { fileContents.Jar = jar }
+}
+
+// addCookies adds the cookies of a step to the headers of a request, in order of their names
+func addCookies(header http.Header, cookies map[string]any) {
+	names := make([]string, 0, len(cookies))
+	for name := range cookies { names = append(names, name) }
+	sort.Strings(names)
+
+	request := &http.Request{Header: header}
+	for _, name := range names { request.AddCookie(&http.Cookie{Name: name, Value: fmt.Sprint(cookies[name])}) }
+}
+
+// jarCookies adds the cookies the jar holds for a url to the headers of a
+// request that is not sent through httpClient, such as a websocket
+func jarCookies(fileContents *cmd.Structure, header http.Header, location string) {
+	if fileContents.Jar == nil { return }
+
+	u, err := url.Parse(location)
+	if err != nil { return }
+	request := &http.Request{Header: header}
+	for _, cookie := range fileContents.Jar.Cookies(u) { request.AddCookie(cookie) }
+}
+
+// checkCookies fails a step whose response does not set the expected cookies
+func checkCookies(expected map[string]string, res cmd.APIResponse) error {
+	set := map[string]string{}
+	for _, cookie := range (&http.Response{Header: res.Headers}).Cookies() { set[cookie.Name] = cookie.Value }
+
+	names := make([]string, 0, len(expected))
+	for name := range expected { names = append(names, name) }
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, exists := set[name]
+		if !exists { return fmt.Errorf("Expected cookie %s to be set", name) }
+		if expected[name] != "" && value != expected[name] {
+			return fmt.Errorf("Expected cookie %s to be %q, got %q", name, expected[name], value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// sessionServer sets the session cookie to the last segment of /login/<sid>
+// and answers any other path with the session it was sent
+func sessionServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sid, found := strings.CutPrefix(r.URL.Path, "/login/"); found { http.SetCookie(w, &http.Cookie{Name: "sid", Value: sid, Path: "/"}) }
+		sid := ""
+		if cookie, err := r.Cookie("sid"); err == nil { sid = cookie.Value }
+		w.Write([]byte(`{"sid": "` + sid + `"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCookieJarScope(t *testing.T) {
+	server := sessionServer(t)
+
+	// the root beforeAll logs in, the first pipeline logs in again as someone
+	// else and the second one reads the session it is left with
+	for scope, want := range map[string]string{"run": "first", "pipeline": "root", "none": ""} {
+		fileContents := tlsStructure(t, t.TempDir(), server.URL, cmd.TLS{})
+		fileContents.Cookies.Scope = scope
+		fileContents.Hooks.BeforeAll = []cmd.PipelineBody{{Endpoint: "/login/root"}}
+		fileContents.CustomPipelines = map[string]cmd.Pipeline{
+			"a": {Steps: []cmd.PipelineBody{{Endpoint: "/login/first"}}},
+			"b": {Steps: []cmd.PipelineBody{{Endpoint: "/me", Capture: map[string]string{"session": "sid"}}}},
+		}
+		if err := OpenCookieJar(fileContents); err != nil { t.Fatal(err) }
+
+		CallCustomPipelines(fileContents)
+		if got := fileContents.Variables["session"]; got != want { t.Errorf("with the %s scope the second pipeline sent the session %q, want %q", scope, got, want) }
+	}
+}
+
+func TestCookieJarPersist(t *testing.T) {
+	server := sessionServer(t)
+	dir := t.TempDir()
+
+	run := func(environment string, endpoint string) *cmd.Structure {
+		fileContents := tlsStructure(t, dir, server.URL, cmd.TLS{})
+		fileContents.BaseURL.Production = server.URL
+		if err := fileContents.SelectEnvironment(environment); err != nil { t.Fatal(err) }
+		fileContents.Cookies.Persist = true
+		fileContents.PipelineBody = []cmd.PipelineBody{{Endpoint: endpoint, Capture: map[string]string{"session": "sid"}}}
+
+		if err := OpenCookieJar(fileContents); err != nil { t.Fatal(err) }
+		CallCurrentPipeline(fileContents)
+		if err := SaveCookieJar(fileContents); err != nil { t.Fatal(err) }
+		return fileContents
+	}
+
+	// the jar is kept next to the configuration, apart for every environment
+	run("development", "/login/dev")
+	run("production", "/login/prod")
+	if _, err := os.Stat(filepath.Join(dir, CookieFile)); err != nil { t.Fatalf("the cookies were not saved next to the configuration: %v", err) }
+	if _, err := os.Stat(CookieFile); err == nil { t.Errorf("the cookies were saved in the working directory") }
+
+	for environment, want := range map[string]string{"development": "dev", "production": "prod"} {
+		if got := run(environment, "/me").Variables["session"]; got != want { t.Errorf("the next %s run sent the session %q, want %q", environment, got, want) }
+	}
+}
@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/expr"
+)
+
+// checkResponse fails a step whose response differs from what the step
+// requires: its redirects, its cookies, a gRPC code other than OK or the
+// expected one or, for GraphQL, a non empty errors list
+func checkResponse(step cmd.PipelineBody, res cmd.APIResponse) error {
+	// a gRPC call has to succeed unless its failure code is expected
+	if step.Kind == "grpc" && res.StatusCode != 0 && res.StatusCode != step.ExpectedStatusCode {
+		return fmt.Errorf("gRPC call failed with %s: %s", res.Body.Path("code").Data(), res.Body.Path("message").Data())
+	}
+
+	if step.GraphQL != nil {
+		if errors := res.Body.Path("errors").Children(); len(errors) > 0 {
+			messages := make([]string, len(errors))
+			for i, item := range errors {
+				messages[i] = item.String()
+				if message, ok := item.Path("message").Data().(string); ok { messages[i] = message }
+			}
+			return fmt.Errorf("GraphQL response has errors:\n  %s", strings.Join(messages, "\n  "))
+		}
+	}
+
+	if len(step.ExpectedRedirects) > 0 {
+		if err := checkRedirects(step.ExpectedRedirects, res.Redirects); err != nil { return err }
+	}
+
+	if len(step.ExpectedCookies) > 0 {
+		return checkCookies(step.ExpectedCookies, res)
+	}
+	return nil
+}
+
+// checkRedirects fails a step not redirected through the expected urls in
+// order. An expected path, starting with /, only has to match the path and
+// query of the url
+func checkRedirects(expected []string, redirects []string) error {
+	matches := len(expected) == len(redirects)
+	for i := 0; matches && i < len(expected); i++ {
+		matches = expected[i] == redirects[i]
+		if parsed, err := url.Parse(redirects[i]); !matches && err == nil && strings.HasPrefix(expected[i], "/") {
+			matches = expected[i] == parsed.RequestURI()
+		}
+	}
+
+	if matches { return nil }
+	if len(redirects) == 0 { return fmt.Errorf("Expected redirects %s, got none", strings.Join(expected, " -> ")) }
+	return fmt.Errorf("Expected redirects %s, got %s", strings.Join(expected, " -> "), strings.Join(redirects, " -> "))
+}
+
+// checkAssertions fails a step when any of its assert expressions does not
+// hold for its response
+func checkAssertions(fileContents *cmd.Structure, step cmd.PipelineBody, res cmd.APIResponse) error {
+	if len(step.Assert) == 0 { return nil }
+
+	env := responseEnvironment(fileContents.Variables, &res)
+	for _, source := range step.Assert {
+		assertion, err := expr.Parse(source)
+		if err != nil { return fmt.Errorf("Could not read assertion: %v", err) }
+
+		holds, err := assertion.Bool(env)
+		if err != nil { return fmt.Errorf("Could not evaluate assertion: %v", err) }
+		if !holds {
+			// showing the phases of the request when the assertion reads them
+			for _, name := range assertion.Names() {
+				if name == "duration" { return fmt.Errorf("Assertion failed: %s, timings were %s", source, res.Timings) }
+			}
+			return fmt.Errorf("Assertion failed: %s", source)
+		}
+	}
+	return nil
+}
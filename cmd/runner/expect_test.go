@@ -0,0 +1,37 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/Jeffail/gabs/v2"
+)
+
+func TestCheckResponseGraphQL(t *testing.T) {
+	step := cmd.PipelineBody{Endpoint: "/graphql", GraphQL: &cmd.GraphQL{Query: "{ me { id } }"}}
+
+	responses := map[string]string{
+		`{"data": {"me": {"id": 1}}}`: "",
+		`{"data": {"me": null}, "errors": []}`: "",
+		`{"data": null, "errors": [{"message": "not signed in"}, {"message": "me is null", "path": ["me"]}]}`: "GraphQL response has errors:\n  not signed in\n  me is null",
+		`{"errors": [{"extensions": {"code": "INTERNAL"}}]}`: "GraphQL response has errors:\n  {\"extensions\":{\"code\":\"INTERNAL\"}}",
+	}
+	for body, want := range responses {
+		parsed, err := gabs.ParseJSON([]byte(body))
+		if err != nil { t.Fatal(err) }
+
+		// a 200 status code does not make a response with errors pass
+		err = checkResponse(step, cmd.APIResponse{StatusCode: 200, Body: parsed})
+		if got := errorText(err); got != want { t.Errorf("checkResponse(%s) = %q, want %q", body, got, want) }
+	}
+
+	// errors only mean something for a GraphQL step
+	parsed, _ := gabs.ParseJSON([]byte(`{"errors": [{"message": "field is required"}]}`))
+	if err := checkResponse(cmd.PipelineBody{Endpoint: "/form"}, cmd.APIResponse{StatusCode: 422, Body: parsed}); err != nil { t.Errorf("checkResponse() failed a rest step on its errors: %v", err) }
+}
+
+// errorText is the message of an error, empty for none
+func errorText(err error) string {
+	if err == nil { return "" }
+	return err.Error()
+}
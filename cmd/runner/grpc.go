@@ -0,0 +1,239 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+	"github.com/Jeffail/gabs/v2"
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// methods caches the resolved gRPC methods so reflection is only asked once per run
+var (
+	methods = map[string]protoreflect.MethodDescriptor{}
+	methodsLock sync.Mutex
+)
+
+// CallGRPC makes the unary gRPC call of a step. The json message is
+// converted with the descriptors of the method, and the response is turned
+// back into json so expectations and captures work as they do for HTTP.
+// The status code of the response is the gRPC code, 0 being OK
+func CallGRPC(fileContents *cmd.Structure, call cmd.GRPC, structure cmd.APIStructure) (cmd.APIResponse, error) {
+
+	startTime := time.Now()
+	defaults := fileContents.ActiveDefaults()
+
+	// finding the server from the step or the base url
+	address, secure, err := grpcAddress(fileContents.ActiveURL, call.Address)
+	if err != nil { return cmd.APIResponse{}, err }
+	if fileContents.ActiveSocket != "" && call.Address == "" { address = "unix://" + fileContents.ActiveSocket }
+
+	transport := insecure.NewCredentials()
+	if secure {
+		config, err := tlsConfig(fileContents)
+		if err != nil { return cmd.APIResponse{}, err }
+		transport = credentials.NewTLS(config)
+	}
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(transport))
+	if err != nil { return cmd.APIResponse{}, fmt.Errorf("Could not connect to %s: %v", address, err) }
+	defer conn.Close()
+
+	ctx, cancel, err := requestContext(structure.Timeout, defaults)
+	if err != nil { return cmd.APIResponse{}, err }
+	defer cancel()
+
+	method, err := resolveMethod(ctx, conn, address, fileContents.ConfigDir, call)
+	if err != nil { return cmd.APIResponse{}, err }
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return cmd.APIResponse{}, fmt.Errorf("Could not call %s/%s, only unary methods are supported", call.Service, call.Method)
+	}
+
+	// converting the json message into the input type of the method
+	request := dynamicpb.NewMessage(method.Input())
+	if call.Message != nil {
+		message, err := json.Marshal(call.Message)
+		if err != nil { return cmd.APIResponse{}, err }
+		if err := protojson.Unmarshal(message, request); err != nil {
+			return cmd.APIResponse{}, fmt.Errorf("Could not convert message to %s: %v", method.Input().FullName(), err)
+		}
+	}
+
+	// sending the headers as metadata, gRPC wants their keys in lower case
+	pairs := []string{}
+	for key, values := range requestHeaders(fileContents, defaults, structure.Headers) {
+		for _, value := range values { pairs = append(pairs, strings.ToLower(key), value) }
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+
+	response := dynamicpb.NewMessage(method.Output())
+	fullMethod := fmt.Sprintf("/%s/%s", call.Service, call.Method)
+	callErr := conn.Invoke(ctx, fullMethod, request, response)
+
+	// failed calls answer with their status instead of a message
+	code, body := 0, []byte{}
+	if callErr != nil {
+		failure, ok := status.FromError(callErr)
+		if !ok { return cmd.APIResponse{}, callErr }
+
+		code = int(failure.Code())
+		body, _ = json.Marshal(map[string]any{"code": failure.Code().String(), "message": failure.Message()})
+	} else {
+		body, err = protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(response)
+		if err != nil { return cmd.APIResponse{}, err }
+	}
+
+	data, err := gabs.ParseJSON(body)
+	if err != nil { return cmd.APIResponse{}, err }
+
+	structure.Method = "GRPC"
+	utils.ResponseLogger(structure, code, address + fullMethod, time.Since(startTime))
+
+	return cmd.APIResponse{
+		StatusCode: code,
+		Body: data,
+	}, nil
+}
+
+// grpcAddress picks the host:port to dial, http and grpc base urls are
+// dialed in plaintext while https and grpcs ones use TLS
+func grpcAddress(baseURL string, override string) (string, bool, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Host == "" {
+		if override == "" { return "", false, fmt.Errorf("Could not find a gRPC server in base url %q, set grpc.address", baseURL) }
+		return override, false, nil
+	}
+
+	secure := parsed.Scheme == "https" || parsed.Scheme == "grpcs"
+	if override != "" { return override, secure, nil }
+
+	address := parsed.Host
+	if parsed.Port() == "" && secure { address += ":443" }
+	if parsed.Port() == "" && !secure { address += ":80" }
+	return address, secure, nil
+}
+
+// resolveMethod finds the descriptor of a method in the proto files of the
+// step, or asks the reflection service of the server when there are none
+func resolveMethod(ctx context.Context, conn *grpc.ClientConn, address string, configDir string, call cmd.GRPC) (protoreflect.MethodDescriptor, error) {
+	key := strings.Join(append([]string{address, call.Service, call.Method}, call.Protos...), "|")
+
+	methodsLock.Lock()
+	defer methodsLock.Unlock()
+	if method, exists := methods[key]; exists { return method, nil }
+
+	var descriptor protoreflect.Descriptor
+	var err error
+	if len(call.Protos) > 0 {
+		descriptor, err = descriptorFromProtos(ctx, configDir, call)
+	} else {
+		descriptor, err = descriptorFromReflection(ctx, conn, call.Service)
+	}
+	if err != nil { return nil, err }
+
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok { return nil, fmt.Errorf("Could not find service %s", call.Service) }
+
+	method := service.Methods().ByName(protoreflect.Name(call.Method))
+	if method == nil { return nil, fmt.Errorf("Could not find method %s in service %s", call.Method, call.Service) }
+
+	methods[key] = method
+	return method, nil
+}
+
+// descriptorFromProtos compiles the proto files of a step and looks the service up
+func descriptorFromProtos(ctx context.Context, configDir string, call cmd.GRPC) (protoreflect.Descriptor, error) {
+	importPaths := []string{}
+	for _, path := range call.ImportPaths {
+		if !filepath.IsAbs(path) { path = filepath.Join(configDir, path) }
+		importPaths = append(importPaths, path)
+	}
+	importPaths = append(importPaths, configDir)
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+	}
+	files, err := compiler.Compile(ctx, call.Protos...)
+	if err != nil { return nil, fmt.Errorf("Could not compile proto files: %v", err) }
+
+	descriptor, err := files.AsResolver().FindDescriptorByName(protoreflect.FullName(call.Service))
+	if err != nil { return nil, fmt.Errorf("Could not find service %s in %s", call.Service, strings.Join(call.Protos, ", ")) }
+	return descriptor, nil
+}
+
+// descriptorFromReflection fetches the file declaring a service, along with
+// every file it depends on, from the reflection service of the server
+func descriptorFromReflection(ctx context.Context, conn *grpc.ClientConn, service string) (protoreflect.Descriptor, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil { return nil, fmt.Errorf("Could not use server reflection: %v", err) }
+	defer stream.CloseSend()
+
+	files := map[string]*descriptorpb.FileDescriptorProto{}
+	ask := func(request *reflectionpb.ServerReflectionRequest) error {
+		if err := stream.Send(request); err != nil { return err }
+
+		response, err := stream.Recv()
+		if err == io.EOF { return fmt.Errorf("reflection stream closed") }
+		if err != nil { return err }
+		if failure := response.GetErrorResponse(); failure != nil { return fmt.Errorf("%s", failure.GetErrorMessage()) }
+
+		for _, raw := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			file := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(raw, file); err != nil { return err }
+			files[file.GetName()] = file
+		}
+		return nil
+	}
+
+	err = ask(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service}})
+	if err != nil { return nil, fmt.Errorf("Could not find service %s through server reflection: %v", service, err) }
+
+	// asking for the dependencies the server did not send along
+	for missing := missingDependencies(files); len(missing) > 0; missing = missingDependencies(files) {
+		for _, name := range missing {
+			err := ask(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: name}})
+			if err != nil { return nil, fmt.Errorf("Could not fetch %s through server reflection: %v", name, err) }
+			if files[name] == nil { return nil, fmt.Errorf("Could not fetch %s through server reflection", name) }
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, file := range files { set.File = append(set.File, file) }
+
+	registry, err := protodesc.NewFiles(set)
+	if err != nil { return nil, fmt.Errorf("Could not read descriptors of %s: %v", service, err) }
+
+	return registry.FindDescriptorByName(protoreflect.FullName(service))
+}
+
+// missingDependencies lists the imported files that were not fetched yet
+func missingDependencies(files map[string]*descriptorpb.FileDescriptorProto) []string {
+	missing := []string{}
+	for _, file := range files {
+		for _, dependency := range file.GetDependency() {
+			if files[dependency] == nil { missing = append(missing, dependency) }
+		}
+	}
+	return missing
+}
@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const usersProto = `syntax = "proto3";
+package users;
+
+service Users {
+  rpc Get(GetRequest) returns (User);
+  rpc Watch(GetRequest) returns (stream User);
+}
+
+message GetRequest { int64 id = 1; }
+message User {
+  int64 id = 1;
+  string name = 2;
+  repeated string roles = 3;
+}
+`
+
+// servedServices advertises the services of the test server to reflection
+type servedServices map[string]grpc.ServiceInfo
+
+func (s servedServices) GetServiceInfo() map[string]grpc.ServiceInfo { return s }
+
+// grpcServer serves users.Users out of its proto file, along with the
+// reflection service, and returns the directory of the proto and its address
+func grpcServer(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.proto"), []byte(usersProto), 0644); err != nil { t.Fatal(err) }
+
+	files, err := (&protocompile.Compiler{Resolver: &protocompile.SourceResolver{ImportPaths: []string{dir}}}).Compile(context.Background(), "users.proto")
+	if err != nil { t.Fatal(err) }
+	service := files[0].Services().ByName("Users")
+
+	// Get answers with the user of the id, named after the team in the metadata
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		request := dynamicpb.NewMessage(service.Methods().ByName("Get").Input())
+		if err := stream.RecvMsg(request); err != nil { return err }
+
+		id := request.Get(request.Descriptor().Fields().ByName("id")).Int()
+		if id == 0 { return status.Error(codes.NotFound, "no user 0") }
+
+		incoming, _ := metadata.FromIncomingContext(stream.Context())
+		user := dynamicpb.NewMessage(service.Methods().ByName("Get").Output())
+		user.Set(user.Descriptor().Fields().ByName("id"), protoreflect.ValueOfInt64(id))
+		user.Set(user.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString("Jane of " + strings.Join(incoming.Get("x-team"), ",")))
+		return stream.SendMsg(user)
+	}))
+	reflectionpb.RegisterServerReflectionServer(server, reflection.NewServerV1(reflection.ServerOptions{
+		Services: servedServices{"users.Users": {}},
+		DescriptorResolver: files.AsResolver(),
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil { t.Fatal(err) }
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return dir, listener.Addr().String()
+}
+
+func TestCallGRPC(t *testing.T) {
+	dir, address := grpcServer(t)
+	fileContents := tlsStructure(t, dir, "grpc://" + address, cmd.TLS{})
+	request := cmd.APIStructure{Headers: map[string]any{"X-Team": "core"}}
+
+	// the method is found in the proto files or else through reflection
+	for _, protos := range [][]string{{"users.proto"}, nil} {
+		call := cmd.GRPC{Service: "users.Users", Method: "Get", Protos: protos, Message: map[string]any{"id": float64(7)}}
+		res, err := CallGRPC(fileContents, call, request)
+		if err != nil { t.Errorf("CallGRPC() with protos %v returned %v", protos, err); continue }
+
+		want := `{"id":"7","name":"Jane of core","roles":[]}`
+		if res.StatusCode != 0 || res.Body.String() != want { t.Errorf("CallGRPC() with protos %v = %d %s, want 0 %s", protos, res.StatusCode, res.Body.String(), want) }
+	}
+
+	// a failed call answers with its status, failing the step unless expected
+	call := cmd.GRPC{Service: "users.Users", Method: "Get", Message: map[string]any{"id": float64(0)}}
+	res, err := CallGRPC(fileContents, call, request)
+	if err != nil { t.Fatal(err) }
+	if res.StatusCode != int(codes.NotFound) || res.Body.String() != `{"code":"NotFound","message":"no user 0"}` { t.Errorf("CallGRPC() of a missing user = %d %s", res.StatusCode, res.Body.String()) }
+
+	step := cmd.PipelineBody{Kind: "grpc", GRPC: &call}
+	if err := checkResponse(step, res); err == nil { t.Errorf("checkResponse() passed a NotFound call") }
+	step.ExpectedStatusCode = int(codes.NotFound)
+	if err := checkResponse(step, res); err != nil { t.Errorf("checkResponse() failed an expected NotFound call: %v", err) }
+}
+
+func TestCallGRPCErrors(t *testing.T) {
+	dir, address := grpcServer(t)
+	fileContents := tlsStructure(t, dir, "grpc://" + address, cmd.TLS{})
+
+	calls := map[string]cmd.GRPC{
+		"only unary methods are supported": {Service: "users.Users", Method: "Watch"},
+		"Could not find method Delete": {Service: "users.Users", Method: "Delete", Protos: []string{"users.proto"}},
+		"Could not find service users.Groups": {Service: "users.Groups", Method: "Get"},
+		"Could not convert message to users.GetRequest": {Service: "users.Users", Method: "Get", Message: map[string]any{"id": "seven"}},
+		"Could not compile proto files": {Service: "users.Users", Method: "Get", Protos: []string{"missing.proto"}},
+	}
+	for want, call := range calls {
+		_, err := CallGRPC(fileContents, call, cmd.APIStructure{})
+		if err == nil || !strings.Contains(err.Error(), want) { t.Errorf("CallGRPC(%s/%s) returned %v, want %q", call.Service, call.Method, err, want) }
+	}
+}
+
+func TestGRPCAddress(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		override string
+		address string
+		secure bool
+	}{
+		{"http://api.example.com", "", "api.example.com:80", false},
+		{"https://api.example.com/v1", "", "api.example.com:443", true},
+		{"grpcs://api.example.com:8443", "", "api.example.com:8443", true},
+		{"grpc://localhost:50051", "", "localhost:50051", false},
+		{"https://api.example.com", "grpc.example.com:9000", "grpc.example.com:9000", true},
+		{"not a url", "localhost:50051", "localhost:50051", false},
+	}
+
+	for _, test := range tests {
+		address, secure, err := grpcAddress(test.baseURL, test.override)
+		if err != nil || address != test.address || secure != test.secure { t.Errorf("grpcAddress(%q, %q) = %s, %v, %v, want %s, %v", test.baseURL, test.override, address, secure, err, test.address, test.secure) }
+	}
+
+	if _, _, err := grpcAddress("not a url", ""); err == nil { t.Errorf("grpcAddress() found a server in a base url without a host") }
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+	"github.com/Jeffail/gabs/v2"
+)
+
+// Hit acts as an HTTP client and hits a rest based request
+func Hit(fileContents *cmd.Structure, structure cmd.APIStructure) (cmd.APIResponse, error) {
+
+	startTime := time.Now()
+	if structure.Method == "" { structure.Method = "GET" }
+
+	// defaults of the run and the active environment sit beneath the step
+	defaults := fileContents.ActiveDefaults()
+
+	// forming complete url with endpoint and query parameters
+	url, err := WithQuery(fileContents.ActiveURL + structure.Endpoint, interpolateQuery(defaults.Query, fileContents.Variables), structure.Query)
+	if err != nil { return cmd.APIResponse{}, err }
+
+	// forming request body according to its type
+	body, contentType, err := requestBody(fileContents.BaseDir(structure.Dir), structure.BodyType, structure.Body)
+	if err != nil { return cmd.APIResponse{}, err }
+
+	// bounding the request by the step timeout, else the default one
+	ctx, cancel, err := requestContext(structure.Timeout, defaults)
+	if err != nil { return cmd.APIResponse{}, err }
+	defer cancel()
+
+	// forming HTTP request, traced to time each of its phases
+	trace := newTracer()
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace.clientTrace()), structure.Method, url, body)
+	if err != nil { return cmd.APIResponse{}, err }
+
+	// adding appropriate headers, the ones of the user take precedence
+	req.Header = requestHeaders(fileContents, defaults, structure.Headers)
+	if contentType != "" && req.Header.Get("Content-Type") == "" { req.Header.Set("Content-Type", contentType) }
+	addCookies(req.Header, structure.Cookies)
+
+	// hitting the server with the request, the cookie jar adds its cookies
+	client, err := httpClient(fileContents)
+	if err != nil { return cmd.APIResponse{}, err }
+	redirects := []string{}
+	client.CheckRedirect = redirectPolicy(structure.FollowRedirects, &redirects)
+	res, err := client.Do(req)
+	if err != nil { return cmd.APIResponse{}, err }
+
+	// closing body when function is popped from stack
+	defer res.Body.Close()
+
+	// reading the body
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil { return cmd.APIResponse{}, err }
+	timings := trace.done()
+
+	data := responseBody(resBody)
+	elapsedTime := time.Since(startTime)
+
+	// logging result - function stored in `helper.go`
+	utils.ResponseLogger(structure, res.StatusCode, url, elapsedTime)
+	if fileContents.Verbose { utils.TimingsLogger(timings) }
+	
+	// returning response
+	return cmd.APIResponse{
+		StatusCode: res.StatusCode,
+		Body: data,
+		Headers: res.Header,
+		Redirects: redirects,
+		Timings: timings,
+	}, nil
+}
+
+// responseBody parses a body into nice json. An empty body, such as the one
+// of a 204, is null and any other body that is not json is kept as text, so
+// only the expectations reading into it fail
+func responseBody(body []byte) *gabs.Container {
+	if len(bytes.TrimSpace(body)) == 0 { return gabs.Wrap(nil) }
+
+	data, err := gabs.ParseJSON(body)
+	if err != nil { return gabs.Wrap(string(body)) }
+	return data
+}
@@ -0,0 +1,42 @@
+package runner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+func TestResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json": w.Write([]byte(`{"id": 1}`))
+		case "/empty": w.WriteHeader(http.StatusNoContent)
+		case "/blank": w.Write([]byte(" \n"))
+		case "/text": w.Write([]byte("pong"))
+		case "/html": w.WriteHeader(http.StatusBadGateway); w.Write([]byte("<h1>Bad Gateway</h1>"))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		endpoint string
+		status int
+		want any
+	}{
+		{"/json", http.StatusOK, map[string]any{"id": float64(1)}},
+		{"/empty", http.StatusNoContent, nil},
+		{"/blank", http.StatusOK, nil},
+		{"/text", http.StatusOK, "pong"},
+		{"/html", http.StatusBadGateway, "<h1>Bad Gateway</h1>"},
+	}
+
+	for _, test := range tests {
+		res, err := Hit(tlsStructure(t, t.TempDir(), server.URL, cmd.TLS{}), cmd.APIStructure{Endpoint: test.endpoint})
+		if err != nil { t.Errorf("Hit(%s) returned %v", test.endpoint, err); continue }
+		if res.StatusCode != test.status { t.Errorf("Hit(%s) got status code %d, want %d", test.endpoint, res.StatusCode, test.status) }
+		if got := res.Body.Data(); !reflect.DeepEqual(got, test.want) { t.Errorf("Hit(%s) read the body %#v, want %#v", test.endpoint, got, test.want) }
+	}
+}
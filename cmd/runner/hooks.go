@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+)
+
+// rootHooks is the pipeline the hooks of the root are reported under
+const rootHooks = "root"
+
+// runHook calls the steps of a hook in a sequence and stops at the first failure
+func runHook(fileContents *cmd.Structure, hook string, steps []cmd.PipelineBody) error {
+	if len(steps) == 0 { return nil }
+
+	previous := fileContents.ActiveHook
+	fileContents.ActiveHook = hook
+	defer func() { fileContents.ActiveHook = previous }()
+
+	fmt.Printf(utils.Blue + "- Running %s\n" + utils.Reset, hook)
+	for i := range steps {
+		if err := RunStep(fileContents, steps[i]); err != nil {
+			fmt.Println(utils.Red + hook + ": " + err.Error() + utils.Reset); return err
+		}
+	}
+	return nil
+}
+
+// withRootHooks wraps a whole run between the beforeAll and afterAll hooks
+// of the root. The run is left out when beforeAll fails, afterAll always runs
+func withRootHooks(fileContents *cmd.Structure, run func()) {
+	fileContents.ActivePipeline, fileContents.ActiveIteration = rootHooks, ""
+	if runHook(fileContents, "beforeAll", fileContents.BeforeAll) == nil { run() }
+
+	fileContents.ActivePipeline, fileContents.ActiveIteration = rootHooks, ""
+	runHook(fileContents, "afterAll", fileContents.AfterAll)
+}
+
+// runEach runs a step between the beforeEach and afterEach hooks of the root
+// and of its pipeline. The step is left out when a beforeEach fails, the
+// afterEach hooks always run
+func runEach(fileContents *cmd.Structure, pipeline cmd.Pipeline, step cmd.PipelineBody) error {
+	err := runHook(fileContents, "beforeEach", fileContents.BeforeEach)
+	if err == nil { err = runHook(fileContents, "beforeEach", pipeline.BeforeEach) }
+	if err == nil {
+		if err = RunStep(fileContents, step); err != nil { fmt.Println(utils.Red + err.Error() + utils.Reset) }
+	}
+
+	if afterErr := runHook(fileContents, "afterEach", pipeline.AfterEach); err == nil { err = afterErr }
+	if afterErr := runHook(fileContents, "afterEach", fileContents.AfterEach); err == nil { err = afterErr }
+	return err
+}
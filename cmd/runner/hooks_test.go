@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// hookSteps makes a step per path, each failing on anything but a 200
+func hookSteps(paths ...string) []cmd.PipelineBody {
+	steps := []cmd.PipelineBody{}
+	for _, path := range paths { steps = append(steps, cmd.PipelineBody{Endpoint: path, Assert: []string{"status == 200"}}) }
+	return steps
+}
+
+func TestHooks(t *testing.T) {
+	server, hits := pathServer(t)
+
+	// fail replaces a path of the configuration by a failing one
+	scenarios := []struct {
+		name string
+		fail string
+		want string
+	}{
+		{
+			name: "order",
+			want: "/root/beforeAll /orders/beforeAll " +
+				"/root/beforeEach /orders/beforeEach /a /orders/afterEach /root/afterEach " +
+				"/root/beforeEach /orders/beforeEach /b /orders/afterEach /root/afterEach " +
+				"/orders/afterAll /root/afterAll",
+		},
+		{
+			name: "failing step",
+			fail: "/a",
+			want: "/root/beforeAll /orders/beforeAll /root/beforeEach /orders/beforeEach /fail/a /orders/afterEach /root/afterEach /orders/afterAll /root/afterAll",
+		},
+		{
+			name: "failing beforeEach",
+			fail: "/root/beforeEach",
+			want: "/root/beforeAll /orders/beforeAll /fail/root/beforeEach /orders/afterEach /root/afterEach /orders/afterAll /root/afterAll",
+		},
+		{
+			name: "failing afterEach",
+			fail: "/orders/afterEach",
+			want: "/root/beforeAll /orders/beforeAll /root/beforeEach /orders/beforeEach /a /fail/orders/afterEach /root/afterEach /orders/afterAll /root/afterAll",
+		},
+		{
+			name: "failing pipeline beforeAll",
+			fail: "/orders/beforeAll",
+			want: "/root/beforeAll /fail/orders/beforeAll /orders/afterAll /root/afterAll",
+		},
+		{
+			name: "failing root beforeAll",
+			fail: "/root/beforeAll",
+			want: "/fail/root/beforeAll /root/afterAll",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		steps := func(path string) []cmd.PipelineBody {
+			if path == scenario.fail { path = "/fail" + path }
+			return hookSteps(path)
+		}
+
+		fileContents := tlsStructure(t, t.TempDir(), server.URL, cmd.TLS{})
+		fileContents.Hooks = cmd.Hooks{BeforeAll: steps("/root/beforeAll"), AfterAll: steps("/root/afterAll"), BeforeEach: steps("/root/beforeEach"), AfterEach: steps("/root/afterEach")}
+		fileContents.CustomPipelines = map[string]cmd.Pipeline{"orders": {
+			Hooks: cmd.Hooks{BeforeAll: steps("/orders/beforeAll"), AfterAll: steps("/orders/afterAll"), BeforeEach: steps("/orders/beforeEach"), AfterEach: steps("/orders/afterEach")},
+			Steps: append(steps("/a"), steps("/b")...),
+		}}
+
+		before := len(hits())
+		CallSingleCustomPipeline(fileContents, "orders")
+		if got := strings.Join(hits()[before:], " "); got != scenario.want { t.Errorf("%s: the hooks hit\n%s\nwant\n%s", scenario.name, got, scenario.want) }
+
+		// only the failing step is recorded as a failure
+		wantFailures := 0
+		if scenario.fail != "" { wantFailures = 1 }
+		if got := fileContents.Failures(); got != wantFailures { t.Errorf("%s: %d failures recorded, want %d", scenario.name, got, wantFailures) }
+	}
+}
+
+func TestHooksRecorded(t *testing.T) {
+	server, _ := pathServer(t)
+	fileContents := tlsStructure(t, t.TempDir(), server.URL, cmd.TLS{})
+	fileContents.Hooks = cmd.Hooks{BeforeAll: hookSteps("/login"), AfterEach: hookSteps("/fail/audit"), AfterAll: hookSteps("/logout")}
+	fileContents.PipelineBody = []cmd.PipelineBody{{Name: "me", Endpoint: "/me"}, {Name: "never", Endpoint: "/never"}}
+
+	// the root hooks are reported apart from the pipeline, and a failing
+	// afterEach stops the steps after it
+	CallCurrentPipeline(fileContents)
+	want := []string{"root/beforeAll /login ok", "current/me ok", "current/afterEach /fail/audit failed", "root/afterAll /logout ok"}
+	if got := outcomes(fileContents.Results); !reflect.DeepEqual(got, want) { t.Errorf("CallCurrentPipeline() recorded\n%q\nwant\n%q", got, want) }
+}
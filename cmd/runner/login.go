@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+)
+
+// TokenFile is where the login token is cached between runs
+const TokenFile = "token.txt"
+
+// DefaultLoginRoute is hit with the credentials when loginDetails has no route
+const DefaultLoginRoute = "/login"
+
+// Login function logs the user in based on the credentials
+// of the active environment, unless the auth type is none
+func Login(fileContents *cmd.Structure) {
+	if fileContents.LoginDetails.Type == "none" { return }
+
+	fmt.Println(utils.Green + "- Looking for token..." + utils.Reset)
+	// checking if token exists in the file 
+	// if file doesnt exist, generate new token
+	data, err := os.ReadFile(TokenFile)
+	if err != nil { 
+		fmt.Println(utils.Red + "- Token file not found..." + utils.Reset)
+		GetAndStoreToken(fileContents); return
+	}
+
+	// if file exists but is empty, generate new token
+	if string(data) == "" { 
+		fmt.Println(utils.Red + "- Token not present in the document..." + utils.Reset)
+		GetAndStoreToken(fileContents); return 
+	}
+
+	// store token in app state
+	fileContents.LoginDetails.Token = string(data)
+
+	// getting data from /me api
+	fmt.Println(utils.Blue + "- Token found..." + utils.Reset)
+	fmt.Println(utils.Blue + "- Testing for valid token..." + utils.Reset)
+	tokenCheckResponse, err:= Hit(fileContents, cmd.APIStructure{
+		Endpoint: "/me",
+	})
+	if err != nil {fmt.Println(err.Error()); fmt.Println(utils.Red + "Could not hit API, try again..." + utils.Reset); return }
+
+	// if request fails with unauthorized, generate new token
+	if tokenCheckResponse.StatusCode == 401 {
+		fmt.Println(utils.Red + "- Invalid token found..." + utils.Reset)
+		GetAndStoreToken(fileContents)
+		return 
+	}
+
+	fmt.Println(utils.Green + "\nValid token found!!\n" + utils.Reset)
+}
+
+// GetAndStoreToken is a helper function that simply gets the token 
+// from the response and store it into token.txt file
+func GetAndStoreToken(fileContents *cmd.Structure) {
+	
+	credentials := fileContents.Credentials.Development
+	if fileContents.ActiveEnvironment == "development" { credentials = fileContents.Credentials.Development }
+	if fileContents.ActiveEnvironment == "staging" { credentials = fileContents.Credentials.Staging }
+	if fileContents.ActiveEnvironment == "production" { credentials = fileContents.Credentials.Production }
+
+	route := fileContents.LoginDetails.Route
+	if route == "" { route = DefaultLoginRoute }
+
+	fmt.Println(utils.Green + "- Generating and storing new token..." + utils.Reset)
+	// hitting login api with credentials
+	tokenGetResponse, err := Hit(fileContents, cmd.APIStructure{
+		Endpoint: route,
+		Method: "POST",
+		Body: credentials,
+	})
+	if err != nil { fmt.Println(utils.Red + "Could not hit API, try again..." + utils.Reset); return }
+
+	// fetching token form the json response from the given structure in json file
+	token, _ := tokenGetResponse.Body.Path(fileContents.LoginDetails.TokenLocation).Data().(string)
+
+	// storing token in the file and in app state
+	os.WriteFile(TokenFile, []byte(token), 0633)
+	fileContents.LoginDetails.Token = token
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+func TestGetAndStoreToken(t *testing.T) {
+	// the token file is written in the working directory
+	wd, err := os.Getwd()
+	if err != nil { t.Fatal(err) }
+	if err := os.Chdir(t.TempDir()); err != nil { t.Fatal(err) }
+	defer os.Chdir(wd)
+
+	logins := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credentials := map[string]any{}
+		json.NewDecoder(r.Body).Decode(&credentials)
+		logins = append(logins, r.Method + " " + r.URL.Path)
+		if credentials["user"] != "jane" { w.WriteHeader(http.StatusUnauthorized); return }
+		w.Write([]byte(`{"data": {"token": "abc"}}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		route string
+		want string
+	}{
+		{"", "POST /login"},
+		{"/auth/sign-in", "POST /auth/sign-in"},
+	}
+
+	for _, test := range tests {
+		logins = []string{}
+		fileContents := tlsStructure(t, t.TempDir(), server.URL, cmd.TLS{})
+		fileContents.Credentials.Development = map[string]any{"user": "jane"}
+		fileContents.LoginDetails = cmd.LoginDetails{Route: test.route, TokenLocation: "data.token"}
+
+		GetAndStoreToken(fileContents)
+		if len(logins) != 1 || logins[0] != test.want { t.Errorf("GetAndStoreToken() with route %q sent %q, want %s", test.route, logins, test.want) }
+		if fileContents.LoginDetails.Token != "abc" { t.Errorf("GetAndStoreToken() with route %q stored token %q, want abc", test.route, fileContents.LoginDetails.Token) }
+
+		data, err := os.ReadFile(TokenFile)
+		if err != nil || string(data) != "abc" { t.Errorf("GetAndStoreToken() with route %q wrote %q, %v to %s", test.route, data, err, TokenFile) }
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	tests := []struct {
+		token string
+		want []string
+	}{
+		{"", nil},
+		{"abc", []string{"Bearer abc"}},
+	}
+
+	for _, test := range tests {
+		fileContents := &cmd.Structure{LoginDetails: cmd.LoginDetails{Token: test.token}}
+		header := requestHeaders(fileContents, cmd.Defaults{}, nil)
+		if got := header.Values("Authorization"); !reflect.DeepEqual(got, test.want) { t.Errorf("requestHeaders() with token %q sent Authorization %q, want %q", test.token, got, test.want) }
+	}
+}
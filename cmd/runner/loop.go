@@ -0,0 +1,35 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/expr"
+)
+
+// DefaultLoopVariable holds the current item of a forEach without `as`
+const DefaultLoopVariable = "item"
+
+// loopItems evaluates the forEach expression of a step into its items
+func loopItems(fileContents *cmd.Structure, source string) ([]any, error) {
+	expression, err := expr.Parse(source)
+	if err != nil { return nil, fmt.Errorf("Could not read forEach: %v", err) }
+
+	value, err := expression.Eval(responseEnvironment(fileContents.Variables, fileContents.Previous))
+	if err != nil { return nil, fmt.Errorf("Could not evaluate forEach: %v", err) }
+
+	switch items := value.(type) {
+	case []any:
+		return items, nil
+	case nil:
+		return []any{}, nil
+	}
+	return nil, fmt.Errorf("forEach %s is not an array", source)
+}
+
+// stepLabel names a step in messages, by its name or else by its endpoint
+func stepLabel(step cmd.PipelineBody) string {
+	if step.Name != "" { return step.Name }
+	if step.GRPC != nil { return step.GRPC.Service + "/" + step.GRPC.Method }
+	return step.Endpoint
+}
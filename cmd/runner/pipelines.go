@@ -0,0 +1,250 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/expr"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/script"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+)
+
+// RunStep runs a single step: once, or once for each item of its forEach,
+// skipping the runs where its if condition does not hold. Every run is
+// recorded for the summary
+func RunStep(fileContents *cmd.Structure, step cmd.PipelineBody) error {
+	if fileContents.Variables == nil { fileContents.Variables = map[string]any{} }
+	if step.ForEach == "" { return recordStep(fileContents, stepLabel(step), step) }
+
+	items, err := loopItems(fileContents, step.ForEach)
+	if err != nil { fileContents.Record(cmd.StepResult{Step: stepLabel(step), Endpoint: step.Endpoint, Err: err}); return err }
+
+	// restoring the loop variable once the loop is over
+	as := step.As
+	if as == "" { as = DefaultLoopVariable }
+	previous, existed := fileContents.Variables[as]
+	defer func() {
+		if existed { fileContents.Variables[as] = previous } else { delete(fileContents.Variables, as) }
+	}()
+
+	fmt.Printf(utils.Blue + "- Running %s for each of %d %s\n" + utils.Reset, stepLabel(step), len(items), plural(len(items), "item"))
+	for i, item := range items {
+		fileContents.Variables[as] = item
+		position := fmt.Sprintf("(item %d of %d)", i + 1, len(items))
+		if err := recordStep(fileContents, stepLabel(step) + " " + position, step); err != nil { return fmt.Errorf("%s %s", err.Error(), position) }
+	}
+	return nil
+}
+
+// recordStep runs a step once and records how it went under the given
+// label, along with the timings of its response when it got one
+func recordStep(fileContents *cmd.Structure, label string, step cmd.PipelineBody) error {
+	startTime, previous := time.Now(), fileContents.Previous
+	skipped, err := runIf(fileContents, step)
+
+	timings := cmd.Timings{}
+	if fileContents.Previous != nil && fileContents.Previous != previous { timings = fileContents.Previous.Timings }
+	fileContents.Record(cmd.StepResult{Step: label, Endpoint: step.Endpoint, Skipped: skipped, Err: err, Duration: time.Since(startTime), Timings: timings})
+	return err
+}
+
+// runIf runs a step unless its if condition is false, telling if it was skipped
+func runIf(fileContents *cmd.Structure, step cmd.PipelineBody) (bool, error) {
+	if step.If != "" {
+		condition, err := expr.Parse(step.If)
+		if err != nil { return false, fmt.Errorf("Could not read if condition: %v", err) }
+
+		run, err := condition.Bool(responseEnvironment(fileContents.Variables, fileContents.Previous))
+		if err != nil { return false, fmt.Errorf("Could not evaluate if condition: %v", err) }
+		if !run {
+			fmt.Printf(utils.Blue + "- Skipping %s, %s is false\n" + utils.Reset, stepLabel(step), step.If)
+			return true, nil
+		}
+	}
+	return false, runRequest(fileContents, step)
+}
+
+// runRequest interpolates the variables into a step, hits it and captures
+// the requested values from its response. A step waiting for a condition
+// is hit again and again until the condition holds, its postResponse script
+// then runs on the last response
+func runRequest(fileContents *cmd.Structure, step cmd.PipelineBody) error {
+	var res cmd.APIResponse
+	var err, waitErr error
+	if step.WaitUntil != nil {
+		res, waitErr, err = pollStep(fileContents, step)
+	} else {
+		res, err = callStep(fileContents, step)
+	}
+	if err != nil { return err }
+	if step.PostResponse != "" {
+		if err := script.PostResponse(step.PostResponse, &res, fileContents.Variables); err != nil { return err }
+	}
+	fileContents.Previous = &res
+
+	// storing captured values for the steps that follow
+	for name, path := range step.Capture {
+		if value := res.Body.Path(path); value != nil { fileContents.Variables[name] = value.Data() }
+	}
+
+	fmt.Println(res.Body.StringIndent("", "  "))
+	if waitErr != nil { return waitErr }
+	if err := checkResponse(step, res); err != nil { return err }
+	return checkAssertions(fileContents, step, res)
+}
+
+// callStep makes a single request of a step with the client of its kind,
+// once its preRequest script had its say
+func callStep(fileContents *cmd.Structure, step cmd.PipelineBody) (cmd.APIResponse, error) {
+	request := cmd.APIStructure{
+		Endpoint: fmt.Sprint(cmd.Interpolate(step.Endpoint, fileContents.Variables)),
+		Method: step.Method,
+		Body: cmd.Interpolate(step.Body, fileContents.Variables),
+		BodyType: step.BodyType,
+		ExpectedStatusCode: step.ExpectedStatusCode,
+		Headers: cmd.Interpolate(step.Headers, fileContents.Variables),
+		Query: interpolateQuery(step.Query, fileContents.Variables),
+		Cookies: interpolateQuery(step.Cookies, fileContents.Variables),
+		Timeout: step.Timeout,
+		FollowRedirects: step.FollowRedirects,
+		Dir: step.Dir,
+	}
+
+	// GraphQL operations are posted as json
+	if step.GraphQL != nil {
+		request.Body, request.BodyType = step.GraphQL.Payload(fileContents.Variables), "json"
+		if request.Method == "" { request.Method = "POST" }
+	}
+
+	// the message of a gRPC call is its body, for scripts to change it as well
+	if step.Kind == "grpc" { request.Body = cmd.Interpolate(step.GRPC.Message, fileContents.Variables) }
+	if step.PreRequest != "" {
+		if err := script.PreRequest(step.PreRequest, &request, fileContents.Variables); err != nil { return cmd.APIResponse{}, err }
+	}
+
+	var res cmd.APIResponse
+	var err error
+	switch step.Kind {
+	case "grpc":
+		call := *step.GRPC
+		call.Message = request.Body
+		res, err = CallGRPC(fileContents, call, request)
+	case "websocket", "sse":
+		// stream errors already tell which message was missed
+		return Listen(fileContents, step, request)
+	default:
+		res, err = Hit(fileContents, request)
+	}
+	if err != nil { return res, fmt.Errorf("Could not hit API: %s", err.Error()) }
+
+	return res, nil
+}
+
+// RunSteps calls the steps of a pipeline in a sequence between its beforeAll
+// and afterAll hooks and stops at the first failure. afterAll runs even then,
+// so a pipeline always gets to clean up after itself
+func RunSteps(fileContents *cmd.Structure, pipeline cmd.Pipeline) {
+	defer runHook(fileContents, "afterAll", pipeline.AfterAll)
+	if runHook(fileContents, "beforeAll", pipeline.BeforeAll) != nil { return }
+
+	for i := range pipeline.Steps {
+		if runEach(fileContents, pipeline, pipeline.Steps[i]) != nil { return }
+	}
+}
+
+// RunPipeline calls the steps of a pipeline in a sequence. A pipeline with a
+// data file runs its steps, along with its beforeAll and afterAll hooks, once
+// per row, every row starting over from the same variables with its columns
+// added, and a failing row does not stop the rows after it. A pipeline
+// scoped cookie jar starts over for every pipeline
+func RunPipeline(fileContents *cmd.Structure, name string, pipeline cmd.Pipeline) {
+	fileContents.ActivePipeline, fileContents.ActiveIteration = name, ""
+	defer pipelineJar(fileContents)()
+	if pipeline.Data == "" { RunSteps(fileContents, pipeline); return }
+
+	rows, err := cmd.ReadRows(fileContents.Resolve(pipeline.Dir, pipeline.Data))
+	if err != nil {
+		fileContents.Record(cmd.StepResult{Step: pipeline.Data, Err: err})
+		fmt.Println(utils.Red + err.Error() + utils.Reset); return
+	}
+
+	// putting the variables back once every row is done
+	variables := fileContents.Variables
+	defer func() { fileContents.Variables, fileContents.ActiveIteration = variables, "" }()
+
+	for i, row := range rows {
+		fileContents.Variables, fileContents.Previous = map[string]any{}, nil
+		for key, value := range variables { fileContents.Variables[key] = value }
+		for key, value := range row { fileContents.Variables[key] = value }
+
+		fileContents.ActiveIteration = fmt.Sprintf("row %d", i + 1)
+		fmt.Printf(utils.Blue + "\nRunning %s with row %d of %d\n\n" + utils.Reset, name, i + 1, len(rows))
+		RunSteps(fileContents, pipeline)
+	}
+}
+
+// CallCurrentPipeline calls the current pipeline APIs endpoints in a sequence
+func CallCurrentPipeline(fileContents *cmd.Structure) {
+
+	fmt.Println(utils.Blue + "\nCalling All API in current pipeline\n" + utils.Reset)
+	withRootHooks(fileContents, func() {
+		RunPipeline(fileContents, cmd.CurrentPipelineName, cmd.Pipeline{Steps: fileContents.PipelineBody})
+	})
+}
+
+// CallCustomPipelines calls all the custom pipelines APIs endpoints
+func CallCustomPipelines(fileContents *cmd.Structure) {
+	withRootHooks(fileContents, func() {
+		for _, name := range fileContents.PipelineNames()[1:] {
+			RunPipeline(fileContents, name, fileContents.CustomPipelines[name])
+		}
+	})
+}
+
+// CallSingleCustomPipeline calls a single custom pipeline in a sequence
+func CallSingleCustomPipeline(fileContents *cmd.Structure, pipelineKey string) {
+	pipeline, exists := fileContents.CustomPipelines[pipelineKey]
+	if !exists { fmt.Println("No such pipeline exists!!!"); return }
+
+	withRootHooks(fileContents, func() { RunPipeline(fileContents, pipelineKey, pipeline) })
+}
+
+// CallFilteredSteps calls the steps picked by the filter across all the
+// pipelines, along with their hooks. The steps of a pipeline driven by a
+// data file still run once per row, and a failure stops the steps of its
+// own pipeline only. Nothing runs when a selected step uses a variable no
+// step before it captures
+func CallFilteredSteps(fileContents *cmd.Structure, filter cmd.StepFilter) {
+	selected := fileContents.SelectSteps(filter)
+	if len(selected) == 0 { fmt.Println("No steps matched the given filters!!!"); return }
+
+	// a step missing a variable would be sent with its placeholder left in
+	missing := false
+	for _, item := range selected {
+		if len(item.Missing) == 0 { continue }
+		missing = true
+		fileContents.ActivePipeline = item.Pipeline
+		err := fmt.Errorf("Could not run the step, no step running before it captures %s", strings.Join(item.Missing, ", "))
+		fileContents.Record(cmd.StepResult{Step: stepLabel(item.Step), Endpoint: item.Step.Endpoint, Err: err})
+		fmt.Println(utils.Red + stepLabel(item.Step) + ": " + err.Error() + utils.Reset)
+	}
+	if missing { return }
+
+	fmt.Println(utils.Blue + fmt.Sprintf("\nCalling %d selected steps\n", len(selected)) + utils.Reset)
+	withRootHooks(fileContents, func() {
+		for _, name := range fileContents.PipelineNames() {
+			// current is the current pipeline even when a custom one shares its name
+			pipeline := fileContents.CustomPipelines[name]
+			if name == cmd.CurrentPipelineName { pipeline = cmd.Pipeline{} }
+			pipeline.Steps = []cmd.PipelineBody{}
+			for _, item := range selected {
+				if item.Pipeline == name { pipeline.Steps = append(pipeline.Steps, item.Step) }
+			}
+			if len(pipeline.Steps) == 0 { continue }
+
+			RunPipeline(fileContents, name, pipeline)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// pathServer answers every request with its last path segment as the name,
+// with a 500 for the paths holding fail, and lists the paths it was hit on
+func pathServer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var lock sync.Mutex
+	hits := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		hits = append(hits, r.URL.Path)
+		lock.Unlock()
+
+		if strings.Contains(r.URL.Path, "fail") { w.WriteHeader(http.StatusInternalServerError) }
+		fmt.Fprintf(w, `{"name": %q}`, filepath.Base(r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string{}, hits...)
+	}
+}
+
+// outcomes describes the recorded results as pipeline/iteration/step and how it went
+func outcomes(results []cmd.StepResult) []string {
+	described := []string{}
+	for _, result := range results {
+		outcome := "ok"
+		if result.Skipped { outcome = "skipped" }
+		if result.Err != nil { outcome = "failed" }
+		described = append(described, strings.TrimSuffix(result.Pipeline + "/" + result.Iteration, "/") + "/" + result.Step + " " + outcome)
+	}
+	return described
+}
+
+func TestRunPipelineRows(t *testing.T) {
+	server, hits := pathServer(t)
+
+	// the data file sits next to the included file declaring it, not the configuration
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "users"), 0755); err != nil { t.Fatal(err) }
+	files := map[string]string{
+		"users/rows.csv": "name,team\nann,core\nfail-bob,core\ncid,qa\n",
+		"users/rows.json": `[{"name": "dan", "team": "ops"}]`,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil { t.Fatal(err) }
+	}
+
+	tests := map[string]struct {
+		data string
+		want []string
+		hits []string
+	}{
+		"csv": {
+			data: "rows.csv",
+			want: []string{
+				"users/row 1/beforeAll setup ok", "users/row 1/user ok",
+				"users/row 2/beforeAll setup ok", "users/row 2/user failed",
+				"users/row 3/beforeAll setup ok", "users/row 3/user ok",
+			},
+			hits: []string{"/setup", "/teams/core/ann", "/setup", "/teams/core/fail-bob", "/setup", "/teams/qa/cid"},
+		},
+		"json": {
+			data: "rows.json",
+			want: []string{"users/row 1/beforeAll setup ok", "users/row 1/user ok"},
+			hits: []string{"/setup", "/teams/ops/dan"},
+		},
+		"missing": {
+			data: "missing.csv",
+			want: []string{"users/missing.csv failed"},
+			hits: []string{},
+		},
+	}
+
+	for name, test := range tests {
+		before := len(hits())
+		fileContents := tlsStructure(t, dir, server.URL, cmd.TLS{})
+		fileContents.Variables["base"] = "kept"
+
+		// every row starts over and a failing row does not stop the next ones
+		RunPipeline(fileContents, "users", cmd.Pipeline{
+			Data: test.data,
+			Dir: filepath.Join(dir, "users"),
+			Hooks: cmd.Hooks{BeforeAll: []cmd.PipelineBody{{Name: "setup", Endpoint: "/setup"}}},
+			Steps: []cmd.PipelineBody{{Name: "user", Endpoint: "/teams/{{team}}/{{name}}", Capture: map[string]string{"seen": "name"}, Assert: []string{"status == 200"}}},
+		})
+
+		if got := outcomes(fileContents.Results); !reflect.DeepEqual(got, test.want) { t.Errorf("%s: RunPipeline() recorded\n%q\nwant\n%q", name, got, test.want) }
+		if got := hits()[before:]; !reflect.DeepEqual(got, test.hits) { t.Errorf("%s: RunPipeline() hit %q, want %q", name, got, test.hits) }
+
+		// the columns and captures of the rows do not outlive the pipeline
+		if want := map[string]any{"base": "kept"}; !reflect.DeepEqual(fileContents.Variables, want) { t.Errorf("%s: RunPipeline() left the variables %v, want %v", name, fileContents.Variables, want) }
+		if fileContents.ActiveIteration != "" { t.Errorf("%s: RunPipeline() left the iteration %q", name, fileContents.ActiveIteration) }
+	}
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// WithQuery appends the default and step query parameters to an address.
+// Step values override the defaults, a null step value drops the default
+// and a list repeats the parameter once per item
+func WithQuery(address string, defaults map[string]any, query map[string]any) (string, error) {
+	if len(defaults) == 0 && len(query) == 0 { return address, nil }
+
+	merged := map[string]any{}
+	for key, value := range defaults { merged[key] = value }
+	for key, value := range query { merged[key] = value }
+
+	parsed, err := url.Parse(address)
+	if err != nil { return "", fmt.Errorf("Could not add query parameters to %q: %v", address, err) }
+
+	// keeping parameters already written in the endpoint
+	values := parsed.Query()
+
+	keys := make([]string, 0, len(merged))
+	for key := range merged { keys = append(keys, key) }
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		// a default does not add to a parameter the endpoint already has
+		if _, set := query[key]; !set && values.Has(key) { continue }
+
+		switch value := merged[key].(type) {
+		case nil:
+			continue
+		case []any:
+			for _, item := range value { values.Add(key, fmt.Sprint(item)) }
+		default:
+			values.Add(key, fmt.Sprint(value))
+		}
+	}
+
+	parsed.RawQuery = values.Encode()
+	return parsed.String(), nil
+}
+
+// interpolateQuery replaces the variables inside the values of a query map
+func interpolateQuery(query map[string]any, variables map[string]any) map[string]any {
+	if query == nil { return nil }
+	return cmd.Interpolate(query, variables).(map[string]any)
+}
@@ -0,0 +1,26 @@
+package runner
+
+import "testing"
+
+func TestWithQuery(t *testing.T) {
+	defaults := map[string]any{"lang": "en", "page": float64(1)}
+
+	for _, test := range []struct {
+		address string
+		query map[string]any
+		want string
+	}{
+		{"http://api/users", nil, "http://api/users?lang=en&page=1"},
+		{"http://api/users", map[string]any{"page": float64(2), "tag": []any{"a", "b"}}, "http://api/users?lang=en&page=2&tag=a&tag=b"},
+		{"http://api/users", map[string]any{"lang": nil}, "http://api/users?page=1"},
+		{"http://api/users?lang=fr", nil, "http://api/users?lang=fr&page=1"},
+		{"http://api/users?sort=name", map[string]any{"q": "a b&c"}, "http://api/users?lang=en&page=1&q=a+b%26c&sort=name"},
+	} {
+		got, err := WithQuery(test.address, defaults, test.query)
+		if err != nil || got != test.want { t.Errorf("WithQuery(%s, %v) = %s, %v, want %s", test.address, test.query, got, err, test.want) }
+	}
+
+	// nothing to add leaves the address as written
+	if got, _ := WithQuery("http://api/users?b=1&a=2", nil, nil); got != "http://api/users?b=1&a=2" { t.Errorf("WithQuery() without parameters = %s", got) }
+	if _, err := WithQuery("http://api/%zz", defaults, nil); err == nil { t.Errorf("WithQuery() accepted an invalid address") }
+}
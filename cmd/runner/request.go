@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// requestContext bounds a request by the step timeout, else the default one
+func requestContext(timeout string, defaults cmd.Defaults) (context.Context, context.CancelFunc, error) {
+	if timeout == "" { timeout = defaults.Timeout }
+	if timeout == "" { return context.Background(), func() {}, nil }
+
+	limit, err := time.ParseDuration(timeout)
+	if err != nil { return nil, nil, fmt.Errorf("Could not read timeout %q: %v", timeout, err) }
+
+	ctx, cancel := context.WithTimeout(context.Background(), limit)
+	return ctx, cancel, nil
+}
+
+// requestHeaders lists the headers sent whatever the protocol is: the
+// authorization header when there is a token, the default headers and the
+// headers of the step, each one over the previous
+func requestHeaders(fileContents *cmd.Structure, defaults cmd.Defaults, headers any) http.Header {
+	header := http.Header{}
+	if fileContents.LoginDetails.Token != "" { header.Set("Authorization", "Bearer " + fileContents.LoginDetails.Token) }
+
+	names := make([]string, 0, len(defaults.Headers))
+	for key := range defaults.Headers { names = append(names, key) }
+	sort.Strings(names)
+	for _, key := range names {
+		header.Set(key, fmt.Sprint(cmd.Interpolate(defaults.Headers[key], fileContents.Variables)))
+	}
+
+	// adding custom headers from the user
+	if custom, ok := headers.(map[string]interface{}); ok {
+		for key, value := range custom {
+			header.Set(key, fmt.Sprint(value))
+		}
+	}
+
+	return header
+}
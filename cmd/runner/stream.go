@@ -0,0 +1,232 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+	"github.com/Jeffail/gabs/v2"
+	"golang.org/x/net/websocket"
+)
+
+// DefaultStreamTimeout bounds a stream step that sets no timeout of its own
+const DefaultStreamTimeout = "10s"
+
+// streamMessage is a single message received from a websocket or an event stream
+type streamMessage struct {
+	Event string
+	Data string
+}
+
+// Listen runs a websocket or server-sent events step: it connects with the
+// headers Hit would send, sends the scripted messages and waits for the
+// expected ones. The received messages are returned as `messages` so the
+// captures of the step work on them as well
+func Listen(fileContents *cmd.Structure, step cmd.PipelineBody, structure cmd.APIStructure) (cmd.APIResponse, error) {
+
+	startTime := time.Now()
+	defaults := fileContents.ActiveDefaults()
+
+	url, err := WithQuery(fileContents.ActiveURL + structure.Endpoint, interpolateQuery(defaults.Query, fileContents.Variables), structure.Query)
+	if err != nil { return cmd.APIResponse{}, err }
+
+	if structure.Timeout == "" && defaults.Timeout == "" { structure.Timeout = DefaultStreamTimeout }
+	ctx, cancel, err := requestContext(structure.Timeout, defaults)
+	if err != nil { return cmd.APIResponse{}, err }
+	defer cancel()
+
+	header := requestHeaders(fileContents, defaults, structure.Headers)
+	addCookies(header, structure.Cookies)
+	stream := step.Stream
+	if stream == nil { stream = &cmd.Stream{} }
+
+	var receive func() (streamMessage, error)
+	statusCode := 0
+	switch step.Kind {
+	case "websocket":
+		config, err := tlsConfig(fileContents)
+		if err != nil { return cmd.APIResponse{}, err }
+		jarCookies(fileContents, header, url)
+		conn, err := dialWebsocket(ctx, fileContents, url, header, config)
+		if err != nil { return cmd.APIResponse{}, err }
+		defer conn.Close()
+
+		statusCode, structure.Method, url = http.StatusSwitchingProtocols, "WEBSOCKET", conn.Config().Location.String()
+		for _, message := range stream.Send {
+			if err := sendMessage(conn, cmd.Interpolate(message, fileContents.Variables)); err != nil {
+				return cmd.APIResponse{}, fmt.Errorf("Could not send websocket message: %v", err)
+			}
+		}
+		receive = func() (streamMessage, error) {
+			text := ""
+			err := websocket.Message.Receive(conn, &text)
+			return streamMessage{Data: text}, err
+		}
+
+	case "sse":
+		client, err := httpClient(fileContents)
+		if err != nil { return cmd.APIResponse{}, err }
+		res, err := openEventStream(ctx, client, url, header)
+		if err != nil { return cmd.APIResponse{}, err }
+		defer res.Body.Close()
+
+		statusCode, structure.Method = res.StatusCode, "GET"
+		receive = eventReader(res)
+	}
+
+	// waiting for each expected message in turn
+	received := []any{}
+	for i, expectation := range stream.Expect {
+		message, err := awaitMessage(expectation, fileContents.Variables, receive, &received)
+		if err != nil {
+			utils.ResponseLogger(structure, statusCode, url, time.Since(startTime))
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return cmd.APIResponse{}, fmt.Errorf("Timed out waiting for expected message %d of %d, received %d messages", i + 1, len(stream.Expect), len(received))
+			}
+			return cmd.APIResponse{}, fmt.Errorf("Stream closed while waiting for expected message %d of %d: %v", i + 1, len(stream.Expect), err)
+		}
+
+		// storing captured values for the expectations and steps that follow
+		for name, path := range expectation.Capture {
+			if path == "" { fileContents.Variables[name] = message; continue }
+			if value := gabs.Wrap(message).Path(path); value != nil { fileContents.Variables[name] = value.Data() }
+		}
+	}
+
+	utils.ResponseLogger(structure, statusCode, url, time.Since(startTime))
+
+	body := gabs.New()
+	body.Set(received, "messages")
+	return cmd.APIResponse{
+		StatusCode: statusCode,
+		Body: body,
+	}, nil
+}
+
+// awaitMessage reads messages until one satisfies the expectation
+func awaitMessage(expectation cmd.StreamExpectation, variables map[string]any, receive func() (streamMessage, error), received *[]any) (any, error) {
+	match := cmd.Interpolate(expectation.Match, variables)
+
+	for {
+		message, err := receive()
+		if err != nil { return nil, err }
+
+		value := messageValue(message.Data)
+		*received = append(*received, value)
+
+		if expectation.Event != "" && message.Event != expectation.Event { continue }
+		if match != nil && utils.ValidateExpectedBody(match, value) != nil { continue }
+		return value, nil
+	}
+}
+
+// messageValue reads json messages as values and keeps the others as text
+func messageValue(data string) any {
+	var value any
+	if err := json.Unmarshal([]byte(data), &value); err != nil { return data }
+	return value
+}
+
+// dialWebsocket opens a websocket the way Hit reaches the server: through
+// the unix socket or the proxy of the environment, with its TLS settings
+func dialWebsocket(ctx context.Context, fileContents *cmd.Structure, url string, header http.Header, tlsSettings *tls.Config) (*websocket.Conn, error) {
+	location := url
+	if strings.HasPrefix(url, "http") { location = "ws" + strings.TrimPrefix(url, "http") }
+	config, err := websocket.NewConfig(location, fileContents.ActiveURL)
+	if err != nil { return nil, fmt.Errorf("Could not open websocket %s: %v", location, err) }
+	config.Header = header
+
+	proxyOf, err := runProxy(fileContents)
+	if err != nil { return nil, err }
+
+	// the proxy is picked as for the http url the websocket upgrades from
+	secure := config.Location.Scheme == "wss"
+	scheme, port := "http", "80"
+	if secure { scheme, port = "https", "443" }
+	address := config.Location.Host
+	if config.Location.Port() == "" { address = net.JoinHostPort(config.Location.Hostname(), port) }
+
+	raw, err := connect(ctx, fileContents, proxyOf, scheme, address)
+	if err != nil { return nil, fmt.Errorf("Could not open websocket %s: %v", location, err) }
+	if deadline, ok := ctx.Deadline(); ok { raw.SetDeadline(deadline) }
+
+	if secure {
+		settings := &tls.Config{}
+		if tlsSettings != nil { settings = tlsSettings.Clone() }
+		if settings.ServerName == "" { settings.ServerName = config.Location.Hostname() }
+
+		tlsConn := tls.Client(raw, settings)
+		if err := tlsConn.HandshakeContext(ctx); err != nil { raw.Close(); return nil, fmt.Errorf("Could not open websocket %s: %v", location, err) }
+		raw = tlsConn
+	}
+
+	conn, err := websocket.NewClient(config, raw)
+	if err != nil { raw.Close(); return nil, fmt.Errorf("Could not open websocket %s: %v", location, err) }
+
+	// closing the connection once the step runs out of time
+	if deadline, ok := ctx.Deadline(); ok { conn.SetDeadline(deadline) }
+	return conn, nil
+}
+
+// sendMessage writes strings as they are and other values as json
+func sendMessage(conn *websocket.Conn, message any) error {
+	if text, ok := message.(string); ok { return websocket.Message.Send(conn, text) }
+
+	data, err := json.Marshal(message)
+	if err != nil { return err }
+	return websocket.Message.Send(conn, string(data))
+}
+
+// openEventStream starts a server-sent events request
+func openEventStream(ctx context.Context, client *http.Client, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil { return nil, err }
+
+	req.Header = header
+	req.Header.Set("Accept", "text/event-stream")
+
+	res, err := client.Do(req)
+	if err != nil { return nil, fmt.Errorf("Could not open event stream %s: %v", url, err) }
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("Could not open event stream %s, got status code %d", url, res.StatusCode)
+	}
+	return res, nil
+}
+
+// eventReader parses the events of a stream, the data lines of an event
+// are joined and the event is dispatched on the blank line closing it
+func eventReader(res *http.Response) func() (streamMessage, error) {
+	scanner := bufio.NewScanner(res.Body)
+
+	return func() (streamMessage, error) {
+		event, data := streamMessage{}, []string{}
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				if len(data) == 0 { event = streamMessage{}; continue }
+				event.Data = strings.Join(data, "\n")
+				return event, nil
+			}
+
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event": event.Event = value
+			case "data": data = append(data, value)
+			}
+		}
+
+		if err := scanner.Err(); err != nil { return streamMessage{}, err }
+		return streamMessage{}, fmt.Errorf("stream ended")
+	}
+}
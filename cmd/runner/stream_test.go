@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"golang.org/x/net/websocket"
+)
+
+func TestListenWebsocket(t *testing.T) {
+	// the server greets, pings, then echoes the first message along with the team header
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		defer conn.Close()
+		websocket.Message.Send(conn, "welcome")
+		websocket.Message.Send(conn, `{"type": "ping"}`)
+
+		message := ""
+		if err := websocket.Message.Receive(conn, &message); err != nil { return }
+		websocket.Message.Send(conn, fmt.Sprintf(`{"type": "echo", "team": %q, "body": %s}`, conn.Request().Header.Get("X-Team"), message))
+	}))
+	defer server.Close()
+
+	fileContents := tlsStructure(t, t.TempDir(), server.URL, cmd.TLS{})
+	fileContents.Variables["name"] = "Jane"
+	step := cmd.PipelineBody{Kind: "websocket", Endpoint: "/ws", Stream: &cmd.Stream{
+		Send: []any{map[string]any{"hello": "{{name}}"}},
+		Expect: []cmd.StreamExpectation{
+			{Match: "welcome"},
+			{Match: map[string]any{"type": "echo"}, Capture: map[string]string{"echoed": "body.hello", "team": "team"}},
+		},
+	}}
+
+	res, err := Listen(fileContents, step, cmd.APIStructure{Endpoint: "/ws", Headers: map[string]any{"X-Team": "core"}})
+	if err != nil { t.Fatalf("Listen() returned %v", err) }
+
+	// the ping matched nothing and was skipped, yet it is among the messages
+	if res.StatusCode != http.StatusSwitchingProtocols { t.Errorf("Listen() answered %d, want 101", res.StatusCode) }
+	if got := len(res.Body.Path("messages").Children()); got != 3 { t.Errorf("Listen() received %d messages, want 3: %s", got, res.Body.String()) }
+	if fileContents.Variables["echoed"] != "Jane" || fileContents.Variables["team"] != "core" { t.Errorf("Listen() captured %v", fileContents.Variables) }
+}
+
+func TestListenSSE(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" { w.WriteHeader(http.StatusNotAcceptable); return }
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": comment\n\nevent: progress\ndata: {\"done\": 50}\n\nevent: progress\ndata: {\"done\":\ndata: 100}\n\ndata: bye\n\n")
+	}))
+	defer server.Close()
+
+	fileContents := tlsStructure(t, t.TempDir(), server.URL, cmd.TLS{})
+	step := cmd.PipelineBody{Kind: "sse", Endpoint: "/events", Stream: &cmd.Stream{Expect: []cmd.StreamExpectation{
+		{Event: "progress", Match: map[string]any{"done": float64(100)}},
+		{Capture: map[string]string{"last": ""}},
+	}}}
+
+	res, err := Listen(fileContents, step, cmd.APIStructure{Endpoint: "/events"})
+	if err != nil { t.Fatalf("Listen() returned %v", err) }
+
+	// data lines of one event are joined before being read as json
+	want := []any{map[string]any{"done": float64(50)}, map[string]any{"done": float64(100)}, "bye"}
+	if got := res.Body.Path("messages").Data(); !reflect.DeepEqual(got, want) { t.Errorf("Listen() received %v, want %v", got, want) }
+	if fileContents.Variables["last"] != "bye" { t.Errorf("Listen() captured the whole message as %v, want bye", fileContents.Variables["last"]) }
+}
+
+func TestListenFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/quiet":
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case "/short":
+			fmt.Fprint(w, "data: one\n\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	fileContents := tlsStructure(t, t.TempDir(), server.URL, cmd.TLS{})
+
+	failures := []struct {
+		endpoint string
+		want string
+	}{
+		{"/quiet", "Timed out waiting for expected message 1 of 1, received 0 messages"},
+		{"/short", "Stream closed while waiting for expected message 1 of 1: stream ended"},
+		{"/missing", "got status code 404"},
+	}
+	for _, failure := range failures {
+		step := cmd.PipelineBody{Kind: "sse", Endpoint: failure.endpoint, Stream: &cmd.Stream{Expect: []cmd.StreamExpectation{{Match: "two"}}}}
+		_, err := Listen(fileContents, step, cmd.APIStructure{Endpoint: failure.endpoint, Timeout: "200ms"})
+		if err == nil || !strings.Contains(err.Error(), failure.want) { t.Errorf("Listen(%s) returned %v, want %q", failure.endpoint, err, failure.want) }
+	}
+}
@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// tracer measures the phases of the requests of a step. Each phase adds up
+// across the redirects of a step, a reused connection adding nothing to
+// dns, connect and tls
+type tracer struct {
+	lock sync.Mutex
+	timings cmd.Timings
+	start time.Time
+	dnsStart time.Time
+	connectStarts map[string]time.Time
+	tlsStart time.Time
+	wrote time.Time
+	firstByte time.Time
+}
+
+// newTracer starts measuring a step from now on
+func newTracer() *tracer {
+	return &tracer{start: time.Now(), connectStarts: map[string]time.Time{}}
+}
+
+// clientTrace hooks the tracer into the requests of a step
+func (t *tracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.at(&t.dnsStart) },
+		DNSDone: func(httptrace.DNSDoneInfo) { t.since(&t.timings.DNS, &t.dnsStart) },
+		ConnectStart: func(network string, address string) {
+			t.lock.Lock(); defer t.lock.Unlock()
+			t.connectStarts[network + address] = time.Now()
+		},
+		ConnectDone: func(network string, address string, err error) {
+			t.lock.Lock(); defer t.lock.Unlock()
+			// dialing several addresses at once only counts the one that connected
+			if started, exists := t.connectStarts[network + address]; exists && err == nil { t.timings.Connect += time.Since(started) }
+		},
+		TLSHandshakeStart: func() { t.at(&t.tlsStart) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) { t.since(&t.timings.TLS, &t.tlsStart) },
+		WroteRequest: func(httptrace.WroteRequestInfo) { t.at(&t.wrote) },
+		GotFirstResponseByte: func() {
+			t.at(&t.firstByte)
+			t.since(&t.timings.TTFB, &t.wrote)
+		},
+	}
+}
+
+// at marks the time a phase starts
+func (t *tracer) at(mark *time.Time) {
+	t.lock.Lock(); defer t.lock.Unlock()
+	*mark = time.Now()
+}
+
+// since adds the time passed from a mark to a phase
+func (t *tracer) since(phase *time.Duration, mark *time.Time) {
+	t.lock.Lock(); defer t.lock.Unlock()
+	if !mark.IsZero() { *phase += time.Since(*mark) }
+}
+
+// done ends the measure once the last response is read
+func (t *tracer) done() cmd.Timings {
+	t.lock.Lock(); defer t.lock.Unlock()
+	if !t.firstByte.IsZero() { t.timings.Transfer = time.Since(t.firstByte) }
+	t.timings.Total = time.Since(t.start)
+	return t.timings
+}
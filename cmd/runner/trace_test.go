@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+)
+
+// pause is how long the slow server waits before its first byte and again
+// before the rest of its body
+const pause = 50 * time.Millisecond
+
+// slowServer answers /slow after a pause, sending the rest of its body after
+// another one, and redirects /redirect to it
+func slowServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" { http.Redirect(w, r, "/slow", http.StatusFound); return }
+
+		time.Sleep(pause)
+		w.Write([]byte(`{"items": [`))
+		w.(http.Flusher).Flush()
+		time.Sleep(pause)
+		w.Write([]byte(`1, 2]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTimings(t *testing.T) {
+	server := slowServer(t)
+
+	// a host name is looked up, unlike the address of the test server
+	fileContents := tlsStructure(t, t.TempDir(), strings.Replace(server.URL, "127.0.0.1", "localhost", 1), cmd.TLS{})
+
+	first, err := Hit(fileContents, cmd.APIStructure{Endpoint: "/slow"})
+	if err != nil { t.Fatal(err) }
+	timings := first.Timings
+	if timings.DNS <= 0 || timings.Connect <= 0 { t.Errorf("the first request timed dns %s and connect %s, want both measured", timings.DNS, timings.Connect) }
+	if timings.TLS != 0 { t.Errorf("a plain http request timed tls %s", timings.TLS) }
+	if timings.TTFB < pause || timings.Transfer < pause { t.Errorf("the first request timed ttfb %s and transfer %s, want both over %s", timings.TTFB, timings.Transfer, pause) }
+	if sum := timings.DNS + timings.Connect + timings.TTFB + timings.Transfer; timings.Total < sum { t.Errorf("the total %s is below the sum of the phases %s", timings.Total, sum) }
+
+	// the connection is reused, and a redirect adds its own wait to the step
+	redirected, err := Hit(fileContents, cmd.APIStructure{Endpoint: "/redirect"})
+	if err != nil { t.Fatal(err) }
+	timings = redirected.Timings
+	if timings.DNS != 0 || timings.Connect != 0 { t.Errorf("a request over a reused connection timed dns %s and connect %s", timings.DNS, timings.Connect) }
+	if len(redirected.Redirects) != 1 || timings.TTFB < pause { t.Errorf("the redirected request went through %v with a ttfb of %s", redirected.Redirects, timings.TTFB) }
+}
+
+func TestTimingsAssert(t *testing.T) {
+	server := slowServer(t)
+	fileContents := tlsStructure(t, t.TempDir(), server.URL, cmd.TLS{})
+
+	if err := RunStep(fileContents, cmd.PipelineBody{Name: "slow", Endpoint: "/slow", Assert: []string{"duration.ttfb >= 50ms", "duration.transfer >= 50ms"}}); err != nil { t.Errorf("RunStep() returned %v", err) }
+
+	// a failing assertion on the timings shows every phase
+	err := RunStep(fileContents, cmd.PipelineBody{Name: "fast", Endpoint: "/slow", Assert: []string{"duration.ttfb < 10ms"}})
+	if text := errorText(err); !strings.HasPrefix(text, "Assertion failed: duration.ttfb < 10ms, timings were dns ") { t.Errorf("RunStep() returned %s", text) }
+
+	// the recorded results keep the timings for the reports
+	for _, result := range fileContents.Results {
+		if result.Timings.TTFB < pause { t.Errorf("%s was recorded with the timings %s", result.Step, result.Timings) }
+	}
+}
@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/expr"
+	"github.com/IbraheemHaseeb7/apee-i/utils"
+)
+
+// DefaultPollInterval and DefaultPollTimeout apply to the steps whose
+// waitUntil leaves them out
+const (
+	DefaultPollInterval = time.Second
+	DefaultPollTimeout = 30 * time.Second
+)
+
+// pollStep hits a step until its waitUntil condition holds on the response.
+// The last response is returned along with a failure when the condition
+// did not hold in time, while errors of the requests stop the polling
+func pollStep(fileContents *cmd.Structure, step cmd.PipelineBody) (cmd.APIResponse, error, error) {
+	condition, err := expr.Parse(step.WaitUntil.Condition)
+	if err != nil { return cmd.APIResponse{}, nil, fmt.Errorf("Could not read waitUntil condition: %v", err) }
+
+	interval, err := durationOf(step.WaitUntil.Interval, DefaultPollInterval)
+	if err != nil { return cmd.APIResponse{}, nil, err }
+	timeout, err := durationOf(step.WaitUntil.Timeout, DefaultPollTimeout)
+	if err != nil { return cmd.APIResponse{}, nil, err }
+
+	startTime := time.Now()
+	for polls := 1; ; polls++ {
+		res, err := callStep(fileContents, step)
+		if err != nil { return res, nil, err }
+
+		met, err := condition.Bool(responseEnvironment(fileContents.Variables, &res))
+		if err != nil { return res, nil, fmt.Errorf("Could not evaluate waitUntil condition: %v", err) }
+
+		if met {
+			fmt.Printf(utils.Blue + "- Condition met after %d %s in %s\n" + utils.Reset, polls, plural(polls, "poll"), time.Since(startTime).Round(time.Millisecond))
+			return res, nil, nil
+		}
+
+		// giving up when the next poll would start past the timeout
+		if time.Since(startTime) + interval > timeout {
+			return res, fmt.Errorf("Condition %s did not hold after %d %s in %s", step.WaitUntil.Condition, polls, plural(polls, "poll"), time.Since(startTime).Round(time.Millisecond)), nil
+		}
+		time.Sleep(interval)
+	}
+}
+
+// durationOf reads a duration option, falling back to a default when it is not set
+func durationOf(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" { return fallback, nil }
+
+	duration, err := time.ParseDuration(value)
+	if err != nil { return 0, fmt.Errorf("Could not read duration %q: %v", value, err) }
+	return duration, nil
+}
+
+// plural adds an s to a word unless there is a single one
+func plural(count int, word string) string {
+	if count == 1 { return word }
+	return word + "s"
+}
+
+// responseEnvironment lets expressions read a response along with the
+// variables, and the phases of its request under `duration`
+func responseEnvironment(variables map[string]any, res *cmd.APIResponse) map[string]any {
+	env, timings := expr.Environment(variables, 0, nil, nil), cmd.Timings{}
+	if res != nil && res.Body != nil { env, timings = expr.Environment(variables, res.StatusCode, res.Body.Data(), res.Headers), res.Timings }
+
+	env["duration"] = timings.Values()
+	return env
+}
@@ -0,0 +1,215 @@
+// Package script runs the preRequest and postResponse scripts of steps.
+// Scripts are written in Starlark, a small dialect of Python, and see the
+// request, the response and the variables as dicts they can change. They
+// have no access to files nor to the network, cannot load other scripts
+// and are stopped when they run for too long
+package script
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/Jeffail/gabs/v2"
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+)
+
+// MaxSteps bounds the work a script can do, so a runaway loop fails its step
+const MaxSteps = 10000000
+
+// names are the values scripts can read beside the builtins and generators
+var names = []string{"request", "response", "vars", "json"}
+
+// Check compiles a script, reporting its syntax errors and the names it
+// uses without defining them
+func Check(name string, source string) error {
+	known := map[string]bool{}
+	for _, predeclared := range names { known[predeclared] = true }
+	for _, generator := range cmd.GeneratorNames() { known[generator] = true }
+
+	_, _, err := starlark.SourceProgramOptions(cmd.ScriptOptions, name, source, func(name string) bool { return known[name] || starlark.Universe.Has(name) })
+	return err
+}
+
+// PreRequest runs a script able to change the request before it is sent,
+// along with the variables
+func PreRequest(source string, request *cmd.APIStructure, variables map[string]any) error {
+	// headers and query are always dicts, so scripts can add to them
+	headers, query := request.Headers, any(request.Query)
+	if headers == nil { headers = map[string]any{} }
+	if request.Query == nil { query = map[string]any{} }
+
+	requestDict := dictOf(map[string]any{
+		"method": request.Method,
+		"url": request.Endpoint,
+		"headers": headers,
+		"query": query,
+		"body": request.Body,
+	})
+
+	if err := run("preRequest", source, starlark.StringDict{"request": requestDict}, variables); err != nil { return err }
+
+	// reading back whatever the script changed
+	values, err := fromStarlark(requestDict)
+	if err != nil { return fmt.Errorf("preRequest script left an invalid request: %v", err) }
+	changed := values.(map[string]any)
+	request.Method, request.Endpoint = fmt.Sprint(changed["method"]), fmt.Sprint(changed["url"])
+	request.Headers, request.Body = changed["headers"], changed["body"]
+	request.Query, _ = changed["query"].(map[string]any)
+
+	return nil
+}
+
+// PostResponse runs a script able to read and change the response before
+// it is captured and checked, along with the variables
+func PostResponse(source string, response *cmd.APIResponse, variables map[string]any) error {
+	headers := map[string]any{}
+	for key, values := range response.Headers { headers[key] = strings.Join(values, ", ") }
+
+	responseDict := dictOf(map[string]any{
+		"status": response.StatusCode,
+		"headers": headers,
+		"body": response.Body.Data(),
+	})
+
+	if err := run("postResponse", source, starlark.StringDict{"response": responseDict}, variables); err != nil { return err }
+
+	// reading back whatever the script changed
+	values, err := fromStarlark(responseDict)
+	if err != nil { return fmt.Errorf("postResponse script left an invalid response: %v", err) }
+	changed := values.(map[string]any)
+
+	status, isNumber := changed["status"].(float64)
+	if !isNumber { return fmt.Errorf("postResponse script left a status that is not a number") }
+	response.StatusCode, response.Body = int(status), gabs.Wrap(changed["body"])
+
+	response.Headers = http.Header{}
+	if changedHeaders, ok := changed["headers"].(map[string]any); ok {
+		for key, value := range changedHeaders { response.Headers.Set(key, fmt.Sprint(value)) }
+	}
+	return nil
+}
+
+// run executes a script with the given values and the variables, storing
+// the variables it changed
+func run(name string, source string, values starlark.StringDict, variables map[string]any) error {
+	varsDict := dictOf(variables)
+
+	predeclared := starlark.StringDict{"vars": varsDict, "json": json.Module}
+	for key, value := range values { predeclared[key] = value }
+	for _, generator := range cmd.GeneratorNames() { predeclared[generator] = builtin(generator) }
+
+	thread := &starlark.Thread{Name: name, Print: func(thread *starlark.Thread, message string) { fmt.Println(message) }}
+	thread.SetMaxExecutionSteps(MaxSteps)
+
+	if _, err := starlark.ExecFileOptions(cmd.ScriptOptions, thread, name, source, predeclared); err != nil {
+		if evalErr, ok := err.(*starlark.EvalError); ok { return fmt.Errorf("%s script failed: %s", name, evalErr.Backtrace()) }
+		return fmt.Errorf("%s script failed: %v", name, err)
+	}
+
+	changed, err := fromStarlark(varsDict)
+	if err != nil { return fmt.Errorf("%s script left an invalid variable: %v", name, err) }
+	for key := range variables { delete(variables, key) }
+	for key, value := range changed.(map[string]any) { variables[key] = value }
+	return nil
+}
+
+// builtin exposes a generator, such as uuid or hmac, as a function of scripts
+func builtin(name string) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if len(kwargs) > 0 { return nil, fmt.Errorf("%s does not take keyword arguments", name) }
+
+		values := make([]any, len(args))
+		for i, arg := range args {
+			value, err := fromStarlark(arg)
+			if err != nil { return nil, fmt.Errorf("%s: %v", name, err) }
+			values[i] = value
+		}
+
+		result, err := cmd.Generate(name, values)
+		if err != nil { return nil, fmt.Errorf("%s: %v", name, err) }
+		return toStarlark(result), nil
+	})
+}
+
+// dictOf turns go values into a dict, keys in order so scripts walk them the same way every run
+func dictOf(values map[string]any) *starlark.Dict {
+	keys := make([]string, 0, len(values))
+	for key := range values { keys = append(keys, key) }
+	sort.Strings(keys)
+
+	dict := starlark.NewDict(len(values))
+	for _, key := range keys { dict.SetKey(starlark.String(key), toStarlark(values[key])) }
+	return dict
+}
+
+// toStarlark converts the values of json documents and variables, whole
+// numbers become ints so scripts can compare them with `==`
+func toStarlark(value any) starlark.Value {
+	switch v := value.(type) {
+	case nil:
+		return starlark.None
+	case bool:
+		return starlark.Bool(v)
+	case string:
+		return starlark.String(v)
+	case int:
+		return starlark.MakeInt(v)
+	case int64:
+		return starlark.MakeInt64(v)
+	case float64:
+		if v == float64(int64(v)) && v < 1 << 53 && v > -(1 << 53) { return starlark.MakeInt64(int64(v)) }
+		return starlark.Float(v)
+	case []any:
+		items := make([]starlark.Value, len(v))
+		for i := range v { items[i] = toStarlark(v[i]) }
+		return starlark.NewList(items)
+	case map[string]any:
+		return dictOf(v)
+	case http.Header:
+		headers := map[string]any{}
+		for key, values := range v { headers[key] = strings.Join(values, ", ") }
+		return dictOf(headers)
+	}
+	return starlark.String(fmt.Sprint(value))
+}
+
+// fromStarlark converts a script value back into the values of json
+// documents, numbers being float64 as in a decoded response
+func fromStarlark(value starlark.Value) (any, error) {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Int:
+		number, _ := starlark.AsFloat(v)
+		return number, nil
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.Indexable:
+		items := make([]any, v.Len())
+		for i := range items {
+			item, err := fromStarlark(v.Index(i))
+			if err != nil { return nil, err }
+			items[i] = item
+		}
+		return items, nil
+	case *starlark.Dict:
+		object := map[string]any{}
+		for _, item := range v.Items() {
+			key, isString := item[0].(starlark.String)
+			if !isString { return nil, fmt.Errorf("keys have to be strings, got %s", item[0].Type()) }
+			converted, err := fromStarlark(item[1])
+			if err != nil { return nil, err }
+			object[string(key)] = converted
+		}
+		return object, nil
+	}
+	return nil, fmt.Errorf("%s values cannot be sent", value.Type())
+}
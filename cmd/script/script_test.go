@@ -0,0 +1,87 @@
+package script
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/Jeffail/gabs/v2"
+)
+
+func TestPreRequest(t *testing.T) {
+	request := cmd.APIStructure{Endpoint: "/orders", Method: "POST", Body: map[string]any{"item": "book", "count": float64(2)}}
+	variables := map[string]any{"secret": "key", "old": true}
+
+	// signing the body, moving the request and keeping what the next steps need
+	source := `
+payload = json.encode(request["body"])
+request["headers"]["X-Signature"] = hmac("sha256", vars["secret"], payload)
+request["query"]["page"] = 2
+request["url"] = request["url"] + "/" + str(request["body"]["count"])
+request["method"] = "PUT"
+vars["signed"] = payload
+vars.pop("old")
+`
+	if err := PreRequest(source, &request, variables); err != nil { t.Fatalf("PreRequest() returned %v", err) }
+
+	signature, _ := cmd.Generate("hmac", []any{"sha256", "key", `{"count":2,"item":"book"}`})
+	if got := request.Headers.(map[string]any)["X-Signature"]; got != signature { t.Errorf("the request was signed with %v, want %v", got, signature) }
+	if request.Method != "PUT" || request.Endpoint != "/orders/2" || request.Query["page"] != float64(2) { t.Errorf("PreRequest() left %s %s %v", request.Method, request.Endpoint, request.Query) }
+	if _, exists := variables["old"]; exists || variables["signed"] != `{"count":2,"item":"book"}` || variables["secret"] != "key" { t.Errorf("PreRequest() left the variables %v", variables) }
+}
+
+func TestPostResponse(t *testing.T) {
+	response := cmd.APIResponse{StatusCode: 202, Headers: http.Header{"Location": {"/jobs/7"}}, Body: gabs.Wrap(map[string]any{"items": []any{float64(1), float64(2), float64(3)}})}
+	variables := map[string]any{}
+
+	source := `
+total = 0
+for item in response["body"]["items"]:
+    total += item
+if response["status"] == 202:
+    response["status"] = 200
+response["body"] = {"total": total}
+response["headers"]["X-Checked"] = "yes"
+vars["job"] = response["headers"]["Location"]
+`
+	if err := PostResponse(source, &response, variables); err != nil { t.Fatalf("PostResponse() returned %v", err) }
+
+	if response.StatusCode != 200 || response.Body.String() != `{"total":6}` { t.Errorf("PostResponse() left %d %s", response.StatusCode, response.Body.String()) }
+	if response.Headers.Get("X-Checked") != "yes" || response.Headers.Get("Location") != "/jobs/7" { t.Errorf("PostResponse() left the headers %v", response.Headers) }
+	if variables["job"] != "/jobs/7" { t.Errorf("PostResponse() stored %v", variables) }
+
+	// a status that is no number is refused
+	err := PostResponse(`response["status"] = "ok"`, &response, variables)
+	if err == nil || !strings.Contains(err.Error(), "status that is not a number") { t.Errorf("PostResponse() with a text status returned %v", err) }
+}
+
+func TestSandbox(t *testing.T) {
+	// nothing outside the values given to scripts can be reached
+	for source, want := range map[string]string{
+		`load("secrets.star", "token")`: "load not implemented",
+		`open("/etc/passwd")`: "undefined: open",
+		`vars["x"] = http.get("http://example.com")`: "undefined: http",
+		`vars["f"] = lambda: 1`: "invalid variable: function values cannot be sent",
+		`vars["u"] = uuid(1)`: "uuid: uuid takes 0 arguments, got 1",
+	} {
+		err := PreRequest(source, &cmd.APIStructure{}, map[string]any{})
+		if err == nil || !strings.Contains(err.Error(), want) { t.Errorf("PreRequest(%s) returned %v, want it to hold %q", source, err, want) }
+	}
+
+	if err := Check("preRequest", "open(vars)"); err == nil || !strings.Contains(err.Error(), "undefined: open") { t.Errorf("Check() returned %v", err) }
+	if err := Check("preRequest", `request["headers"]["Id"] = uuid()`); err != nil { t.Errorf("Check() refused a generator: %v", err) }
+}
+
+func TestMaxSteps(t *testing.T) {
+	// a runaway loop fails its step rather than hanging the run
+	started := time.Now()
+	err := PostResponse("while True:\n    pass\n", &cmd.APIResponse{Body: gabs.New()}, map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), "too many steps") { t.Errorf("PostResponse() on an endless loop returned %v", err) }
+	if elapsed := time.Since(started); elapsed > 30 * time.Second { t.Errorf("the endless loop ran for %s", elapsed) }
+
+	// a loop within the bound still runs
+	variables := map[string]any{}
+	if err := PreRequest("n = 0\nfor i in range(10000):\n    n += i\nvars[\"n\"] = n\n", &cmd.APIStructure{}, variables); err != nil || variables["n"] != float64(49995000) { t.Errorf("PreRequest() stored %v, %v", variables, err) }
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import "go.starlark.net/syntax"
+
+// ScriptOptions allow the loops and conditions a script needs at its top level
+var ScriptOptions = &syntax.FileOptions{While: true, TopLevelControl: true, GlobalReassign: true}
+
+// ScriptAssignments lists the variables a script stores with
+// `vars["name"] = ...`, so they count as defined for the steps that follow
+func ScriptAssignments(name string, source string) []string {
+	file, err := ScriptOptions.Parse(name, source, 0)
+	if err != nil { return nil }
+	return assignedIn(file.Stmts)
+}
+
+// assignedIn walks statements and the blocks nested in them
+func assignedIn(statements []syntax.Stmt) []string {
+	assigned := []string{}
+	for _, statement := range statements {
+		switch stmt := statement.(type) {
+		case *syntax.AssignStmt:
+			target, isIndex := stmt.LHS.(*syntax.IndexExpr)
+			if !isIndex { continue }
+			object, isIdent := target.X.(*syntax.Ident)
+			key, isLiteral := target.Y.(*syntax.Literal)
+			if isIdent && object.Name == "vars" && isLiteral && key.Token == syntax.STRING {
+				assigned = append(assigned, key.Value.(string))
+			}
+		case *syntax.IfStmt:
+			assigned = append(append(assigned, assignedIn(stmt.True)...), assignedIn(stmt.False)...)
+		case *syntax.ForStmt:
+			assigned = append(assigned, assignedIn(stmt.Body)...)
+		case *syntax.WhileStmt:
+			assigned = append(assigned, assignedIn(stmt.Body)...)
+		case *syntax.DefStmt:
+			assigned = append(assigned, assignedIn(stmt.Body)...)
+		}
+	}
+	return assigned
+}
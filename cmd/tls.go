@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// tlsVersions are the versions minVersion accepts
+var tlsVersions = map[string]uint16{
+	"TLS1.0": tls.VersionTLS10,
+	"TLS1.1": tls.VersionTLS11,
+	"TLS1.2": tls.VersionTLS12,
+	"TLS1.3": tls.VersionTLS13,
+}
+
+// Empty tells if no TLS setting is given, the defaults of go then apply
+func (t TLS) Empty() bool {
+	t.Dir = ""
+	return t == TLS{}
+}
+
+// Config builds the TLS configuration of the settings, the files being read
+// relative to dir
+func (t TLS) Config(dir string) (*tls.Config, error) {
+	config := &tls.Config{ServerName: t.ServerName, InsecureSkipVerify: t.InsecureSkipVerify}
+
+	if t.MinVersion != "" {
+		version, exists := tlsVersions[t.MinVersion]
+		if !exists { return nil, fmt.Errorf("Invalid TLS version %s, use TLS1.0, TLS1.1, TLS1.2 or TLS1.3", t.MinVersion) }
+		config.MinVersion = version
+	}
+
+	// trusting the authorities of the bundle along with the system ones
+	if t.CA != "" {
+		data, err := os.ReadFile(relative(dir, t.CA))
+		if err != nil { return nil, fmt.Errorf("Could not read CA bundle %s", t.CA) }
+
+		pool, err := x509.SystemCertPool()
+		if err != nil { pool = x509.NewCertPool() }
+		if !pool.AppendCertsFromPEM(data) { return nil, fmt.Errorf("Could not find any PEM certificate in CA bundle %s", t.CA) }
+		config.RootCAs = pool
+	}
+
+	if (t.Cert == "") != (t.Key == "") { return nil, fmt.Errorf("Client certificate needs both cert and key") }
+	if t.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(relative(dir, t.Cert), relative(dir, t.Key))
+		if err != nil { return nil, fmt.Errorf("Could not load client certificate %s: %v", t.Cert, err) }
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// relative resolves a path relative to dir, absolute paths are kept
+func relative(dir string, path string) string {
+	if filepath.IsAbs(path) { return path }
+	return filepath.Join(dir, path)
+}
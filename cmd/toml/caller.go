@@ -0,0 +1,33 @@
+package toml
+
+import (
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/runner"
+)
+
+// Login is use to login user based on login credentials
+// provided with TOML file. Checks for the environment and
+// then uses credentials accordingly
+func (r *Reader) Login(fileContents *cmd.Structure) {
+	runner.Login(fileContents)
+}
+
+// CallCurrentPipeline calls the current pipeline APIs endpoints in a sequence
+func (r *Reader) CallCurrentPipeline(fileContents *cmd.Structure) {
+	runner.CallCurrentPipeline(fileContents)
+}
+
+// CallCustomPipelines calls all the custom pipelines APIs endpoints
+func (r *Reader) CallCustomPipelines(fileContents *cmd.Structure) {
+	runner.CallCustomPipelines(fileContents)
+}
+
+// CallSingleCustomPipeline calls a single custom pipeline in a sequence
+func (r *Reader) CallSingleCustomPipeline(fileContents *cmd.Structure, pipelineKey string) {
+	runner.CallSingleCustomPipeline(fileContents, pipelineKey)
+}
+
+// CallFilteredSteps calls only the steps selected by the --step, --tags and --grep flags
+func (r *Reader) CallFilteredSteps(fileContents *cmd.Structure, filter cmd.StepFilter) {
+	runner.CallFilteredSteps(fileContents, filter)
+}
@@ -0,0 +1,27 @@
+package toml
+
+import (
+	"github.com/IbraheemHaseeb7/apee-i/cmd"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/config"
+)
+
+// Reader purpose is just to allow the programmer to make selection for TOML
+type Reader struct{}
+
+// ReadInstructions decodes and stores all the instructions from the configuration
+// file in the state of the program. Included files are merged in and the
+// result is validated first so mistakes are reported with their file, line
+// and column
+func (r *Reader) ReadInstructions(filepath string) (*cmd.Structure, error) {
+
+	root, err := config.Load(filepath, config.ParseTOML)
+	if err != nil { return &cmd.Structure{}, err }
+
+	if err := config.Validate(root); err != nil { return &cmd.Structure{}, err }
+
+	fileContents := new(cmd.Structure)
+	if err := config.Decode(root, fileContents); err != nil { return &cmd.Structure{}, err }
+	config.Locate(root, fileContents)
+
+	return fileContents, nil
+}
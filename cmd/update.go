@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultReleaseFeed is where the update command looks for new releases,
+// it can be changed with the --feed flag or the APEE_I_RELEASE_FEED variable
+const DefaultReleaseFeed = "https://github.com/apee-i/apee-i/releases/latest/download/release.json"
+
+// UpdatePublicKey is the base64 ed25519 key release binaries are signed with.
+// It is set at build time with -ldflags, when empty the update is refused
+// unless told to trust the checksums of the feed alone
+var UpdatePublicKey = ""
+
+// ReleaseAsset is a binary published for a single platform
+type ReleaseAsset struct {
+	OS string `json:"os"`
+	Arch string `json:"arch"`
+	URL string `json:"url"`
+	SHA256 string `json:"sha256"`
+	Signature string `json:"signature"`
+}
+
+// ReleaseFeed describes the latest published release
+type ReleaseFeed struct {
+	Version string `json:"version"`
+	Assets []ReleaseAsset `json:"assets"`
+}
+
+// UpdateOptions changes where and how the update command looks for releases
+type UpdateOptions struct {
+	FeedURL string
+	CheckOnly bool
+	Insecure bool
+}
+
+// updateClient is used for the feed and binary downloads
+var updateClient = &http.Client{Timeout: 5 * time.Minute}
+
+// executable finds the binary an update replaces
+var executable = os.Executable
+
+// Update function updates the utility to the latest version
+func Update(options UpdateOptions) error {
+	if options.FeedURL == "" { options.FeedURL = os.Getenv("APEE_I_RELEASE_FEED") }
+	if options.FeedURL == "" { options.FeedURL = DefaultReleaseFeed }
+
+	feed, err := fetchReleaseFeed(options.FeedURL)
+	if err != nil { return err }
+
+	if CompareVersions(feed.Version, CurrentVersion) <= 0 {
+		fmt.Println("apee-i is up to date (" + CurrentVersion + ")")
+		return nil
+	}
+	fmt.Printf("A new version is available: %s -> %s\n", CurrentVersion, feed.Version)
+	if options.CheckOnly { return nil }
+
+	// a checksum read from the feed itself proves nothing about who published it
+	if UpdatePublicKey == "" {
+		if !options.Insecure { return fmt.Errorf("Could not verify the release, this build has no update public key. Download it yourself or pass --insecure to trust %s", options.FeedURL) }
+		fmt.Println("\nWARNING: the release is not signature checked, anyone able to change " + options.FeedURL + " can install any binary\n")
+	}
+
+	// picking the binary built for this platform
+	var asset *ReleaseAsset
+	for i := range feed.Assets {
+		if feed.Assets[i].OS == runtime.GOOS && feed.Assets[i].Arch == runtime.GOARCH { asset = &feed.Assets[i] }
+	}
+	if asset == nil { return fmt.Errorf("no release binary found for %s/%s", runtime.GOOS, runtime.GOARCH) }
+
+	assetURL, err := resolveAssetURL(options.FeedURL, asset.URL)
+	if err != nil { return err }
+
+	fmt.Println("Downloading " + assetURL)
+	binary, err := download(assetURL)
+	if err != nil { return err }
+
+	if err := verifyAsset(binary, asset); err != nil { return err }
+
+	if err := replaceExecutable(binary); err != nil { return err }
+
+	fmt.Println("apee-i updated to " + feed.Version)
+	return nil
+}
+
+// fetchReleaseFeed downloads and decodes the release feed
+func fetchReleaseFeed(feedURL string) (*ReleaseFeed, error) {
+	data, err := download(feedURL)
+	if err != nil { return nil, err }
+
+	feed := &ReleaseFeed{}
+	if err := json.Unmarshal(data, feed); err != nil { return nil, fmt.Errorf("Could not read release feed: %s", err.Error()) }
+	if feed.Version == "" { return nil, fmt.Errorf("release feed has no version") }
+
+	return feed, nil
+}
+
+// download reads the whole body of a GET request
+func download(target string) ([]byte, error) {
+	res, err := updateClient.Get(target)
+	if err != nil { return nil, err }
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK { return nil, fmt.Errorf("Could not download %s: %s", target, res.Status) }
+	return io.ReadAll(res.Body)
+}
+
+// resolveAssetURL allows assets to be given relative to the feed
+func resolveAssetURL(feedURL string, assetURL string) (string, error) {
+	base, err := url.Parse(feedURL)
+	if err != nil { return "", err }
+
+	ref, err := url.Parse(assetURL)
+	if err != nil { return "", err }
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+// verifyAsset checks the checksum and, when a public key is built in, the
+// signature of a binary. Update refuses to get here without a key unless
+// told to
+func verifyAsset(binary []byte, asset *ReleaseAsset) error {
+	if asset.SHA256 == "" { return fmt.Errorf("release binary has no checksum, refusing to install it") }
+
+	sum := sha256.Sum256(binary)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), asset.SHA256) { return fmt.Errorf("checksum mismatch, the download may be corrupted") }
+
+	if UpdatePublicKey == "" { return nil }
+
+	key, err := base64.StdEncoding.DecodeString(UpdatePublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize { return fmt.Errorf("invalid update public key") }
+
+	signature, err := base64.StdEncoding.DecodeString(asset.Signature)
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(key), binary, signature) { return fmt.Errorf("signature mismatch, refusing to install the download") }
+
+	return nil
+}
+
+// replaceExecutable atomically swaps the running binary with the new one by
+// writing next to it and renaming over it
+func replaceExecutable(binary []byte) error {
+	path, err := executable()
+	if err != nil { return err }
+	if path, err = filepath.EvalSymlinks(path); err != nil { return err }
+	return installBinary(path, binary)
+}
+
+// installBinary writes a binary next to an executable and renames it over it
+func installBinary(executable string, binary []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(executable), ".apee-i-update-*")
+	if err != nil { return fmt.Errorf("Could not write next to %s: %s", executable, err.Error()) }
+	defer os.Remove(temp.Name())
+
+	if _, err := io.Copy(temp, bytes.NewReader(binary)); err != nil { temp.Close(); return err }
+	if err := temp.Close(); err != nil { return err }
+	if err := os.Chmod(temp.Name(), 0755); err != nil { return err }
+
+	// windows does not allow renaming over a running executable, it is moved
+	// aside and put back when the new one cannot take its place
+	if runtime.GOOS == "windows" {
+		old := executable + ".old"
+		os.Remove(old)
+		if err := os.Rename(executable, old); err != nil { return err }
+		if err := os.Rename(temp.Name(), executable); err != nil {
+			if restoreErr := os.Rename(old, executable); restoreErr != nil { return fmt.Errorf("Could not install the update: %s, the previous binary is left at %s", err.Error(), old) }
+			return fmt.Errorf("Could not install the update: %s", err.Error())
+		}
+		return nil
+	}
+
+	return os.Rename(temp.Name(), executable)
+}
+
+// CompareVersions compares two `major.minor.patch` versions, an optional
+// leading `v` and any pre-release suffix are ignored
+func CompareVersions(a string, b string) int {
+	left, right := versionParts(a), versionParts(b)
+	for i := range left {
+		if left[i] != right[i] {
+			if left[i] > right[i] { return 1 }
+			return -1
+		}
+	}
+	return 0
+}
+
+// versionParts splits a version into its numeric parts
+func versionParts(version string) [3]int {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version = strings.SplitN(strings.SplitN(version, "-", 2)[0], "+", 2)[0]
+
+	parts := [3]int{}
+	for i, part := range strings.SplitN(version, ".", 3) {
+		parts[i], _ = strconv.Atoi(part)
+	}
+	return parts
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// releaseServer serves a feed announcing a newer version along with its binary
+func releaseServer(t *testing.T, binary []byte, asset ReleaseAsset) *httptest.Server {
+	t.Helper()
+	asset.OS, asset.Arch, asset.URL = runtime.GOOS, runtime.GOARCH, "apee-i-binary"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/release.json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ReleaseFeed{Version: "99.0.0", Assets: []ReleaseAsset{asset}})
+	})
+	mux.HandleFunc("/apee-i-binary", func(w http.ResponseWriter, r *http.Request) { w.Write(binary) })
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// fakeExecutable points updates at a file standing in for the running binary
+func fakeExecutable(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "apee-i")
+	if err := os.WriteFile(path, []byte("old binary"), 0755); err != nil { t.Fatal(err) }
+
+	previous := executable
+	executable = func() (string, error) { return path, nil }
+	t.Cleanup(func() { executable = previous })
+	return path
+}
+
+// signingKey builds a public key into the update for the length of a test
+func signingKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil { t.Fatal(err) }
+
+	previous := UpdatePublicKey
+	UpdatePublicKey = base64.StdEncoding.EncodeToString(public)
+	t.Cleanup(func() { UpdatePublicKey = previous })
+	return private
+}
+
+func checksum(binary []byte) string {
+	sum := sha256.Sum256(binary)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestUpdate(t *testing.T) {
+	binary := []byte("new binary")
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name string
+		signed bool
+		insecure bool
+		asset func(key ed25519.PrivateKey) ReleaseAsset
+		wantErr string
+		installed string
+	}{
+		{
+			name: "signed release",
+			signed: true,
+			asset: func(key ed25519.PrivateKey) ReleaseAsset {
+				return ReleaseAsset{SHA256: checksum(binary), Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, binary))}
+			},
+			installed: "new binary",
+		},
+		{
+			name: "signed by another key",
+			signed: true,
+			asset: func(key ed25519.PrivateKey) ReleaseAsset {
+				return ReleaseAsset{SHA256: checksum(binary), Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, binary))}
+			},
+			wantErr: "signature mismatch",
+			installed: "old binary",
+		},
+		{
+			name: "checksum mismatch",
+			signed: true,
+			asset: func(key ed25519.PrivateKey) ReleaseAsset {
+				return ReleaseAsset{SHA256: checksum([]byte("something else")), Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, binary))}
+			},
+			wantErr: "checksum mismatch",
+			installed: "old binary",
+		},
+		{
+			name: "no public key",
+			asset: func(key ed25519.PrivateKey) ReleaseAsset { return ReleaseAsset{SHA256: checksum(binary)} },
+			wantErr: "no update public key",
+			installed: "old binary",
+		},
+		{
+			name: "no public key but insecure",
+			insecure: true,
+			asset: func(key ed25519.PrivateKey) ReleaseAsset { return ReleaseAsset{SHA256: checksum(binary)} },
+			installed: "new binary",
+		},
+		{
+			name: "no checksum",
+			insecure: true,
+			asset: func(key ed25519.PrivateKey) ReleaseAsset { return ReleaseAsset{} },
+			wantErr: "no checksum",
+			installed: "old binary",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var key ed25519.PrivateKey
+			if test.signed { key = signingKey(t) }
+			path := fakeExecutable(t)
+			server := releaseServer(t, binary, test.asset(key))
+
+			err := Update(UpdateOptions{FeedURL: server.URL + "/release.json", Insecure: test.insecure})
+			if test.wantErr == "" && err != nil { t.Fatalf("Update() returned %v", err) }
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) { t.Fatalf("Update() returned %v, want an error about %q", err, test.wantErr) }
+
+			data, err := os.ReadFile(path)
+			if err != nil { t.Fatal(err) }
+			if string(data) != test.installed { t.Errorf("executable holds %q, want %q", data, test.installed) }
+		})
+	}
+}
+
+func TestUpdateCheckOnly(t *testing.T) {
+	path := fakeExecutable(t)
+	server := releaseServer(t, []byte("new binary"), ReleaseAsset{SHA256: checksum([]byte("new binary"))})
+
+	if err := Update(UpdateOptions{FeedURL: server.URL + "/release.json", CheckOnly: true}); err != nil { t.Fatalf("Update() returned %v", err) }
+	if data, _ := os.ReadFile(path); string(data) != "old binary" { t.Errorf("--check replaced the executable with %q", data) }
+}
+
+func TestUpdateFeedFromEnvironment(t *testing.T) {
+	fakeExecutable(t)
+	server := releaseServer(t, []byte("new binary"), ReleaseAsset{})
+	t.Setenv("APEE_I_RELEASE_FEED", server.URL + "/release.json")
+
+	if err := Update(UpdateOptions{CheckOnly: true}); err != nil { t.Fatalf("Update() returned %v", err) }
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a string
+		b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.2.0", "1.1.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"2.0.0-beta", "2.0.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"0.9.0+build", "1.0.0", -1},
+	}
+
+	for _, test := range tests {
+		if got := CompareVersions(test.a, test.b); got != test.want { t.Errorf("CompareVersions(%q, %q) = %d, want %d", test.a, test.b, got, test.want) }
+	}
+}
+
+func TestResolveAssetURL(t *testing.T) {
+	tests := []struct {
+		feed string
+		asset string
+		want string
+	}{
+		{"http://localhost:8080/releases/release.json", "apee-i_linux_amd64", "http://localhost:8080/releases/apee-i_linux_amd64"},
+		{"http://localhost:8080/releases/release.json", "/bin/apee-i", "http://localhost:8080/bin/apee-i"},
+		{"http://localhost:8080/release.json", "https://example.com/apee-i", "https://example.com/apee-i"},
+	}
+
+	for _, test := range tests {
+		got, err := resolveAssetURL(test.feed, test.asset)
+		if err != nil || got != test.want { t.Errorf("resolveAssetURL(%q, %q) = %q, %v, want %q", test.feed, test.asset, got, err, test.want) }
+	}
+}
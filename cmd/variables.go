@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/IbraheemHaseeb7/apee-i/cmd/expr"
+	"github.com/Jeffail/gabs/v2"
+)
+
+// placeholder matches `{{name}}` references inside endpoints, headers and
+// bodies, along with the arguments of generators such as `{{randomInt 1 100}}`
+var placeholder = regexp.MustCompile(`{{\s*([A-Za-z_][A-Za-z0-9_.\-]*)((?:\s+(?:"(?:[^"\\]|\\.)*"|[^\s"}]+))*)\s*}}`)
+
+// argument matches a single argument of a generator, quoted or not
+var argument = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|[^\s"]+`)
+
+// Interpolate replaces every `{{name}}` placeholder found in the value with
+// the matching variable, or else with the value of the generator by that
+// name. Maps and slices are walked recursively, a string that is nothing
+// but a single placeholder keeps the type of the variable
+func Interpolate(value any, variables map[string]any) any {
+	switch v := value.(type) {
+	case string:
+		// keeping numbers, booleans and objects intact when the whole string is a reference
+		if match := placeholder.FindStringSubmatch(v); match != nil && match[0] == strings.TrimSpace(v) {
+			if resolved, ok := resolvePlaceholder(match[1], match[2], variables); ok { return resolved }
+			return v
+		}
+		return placeholder.ReplaceAllStringFunc(v, func(token string) string {
+			match := placeholder.FindStringSubmatch(token)
+			resolved, ok := resolvePlaceholder(match[1], match[2], variables)
+			if !ok { return token }
+			return fmt.Sprint(resolved)
+		})
+	case map[string]any:
+		// walking the keys in order so a --seed generates the same values
+		keys := make([]string, 0, len(v))
+		for key := range v { keys = append(keys, key) }
+		sort.Strings(keys)
+
+		out := make(map[string]any, len(v))
+		for _, key := range keys { out[key] = Interpolate(v[key], variables) }
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v { out[i] = Interpolate(item, variables) }
+		return out
+	default:
+		return value
+	}
+}
+
+// References lists the names of all the variables a value refers to,
+// generators only refer to the variables given as their arguments
+func References(value any) []string {
+	names := []string{}
+	switch v := value.(type) {
+	case string:
+		for _, match := range placeholder.FindAllStringSubmatch(v, -1) {
+			if _, isGenerator := generators[match[1]]; !isGenerator && match[2] == "" {
+				names = append(names, strings.SplitN(match[1], ".", 2)[0]); continue
+			}
+			for _, arg := range argument.FindAllString(match[2], -1) {
+				if isName(arg) { names = append(names, strings.SplitN(arg, ".", 2)[0]) }
+			}
+		}
+	case map[string]any:
+		for _, item := range v { names = append(names, References(item)...) }
+	case []any:
+		for _, item := range v { names = append(names, References(item)...) }
+	}
+	return names
+}
+
+// StepReferences lists the names of all the variables used by a step
+func StepReferences(step PipelineBody) []string {
+	names := References(step.Endpoint)
+	names = append(names, References(step.Body)...)
+	names = append(names, References(step.Headers)...)
+	for _, value := range step.Query { names = append(names, References(value)...) }
+	for _, value := range step.Cookies { names = append(names, References(value)...) }
+	if step.GraphQL != nil { names = append(names, References(step.GraphQL.Variables)...) }
+	if step.GRPC != nil { names = append(names, References(step.GRPC.Message)...) }
+	for _, source := range []string{step.If, step.ForEach} {
+		if expression, err := expr.Parse(source); source != "" && err == nil { names = append(names, expression.Names()...) }
+	}
+	if step.Stream != nil {
+		names = append(names, References(step.Stream.Send)...)
+		for _, expectation := range step.Stream.Expect { names = append(names, References(expectation.Match)...) }
+	}
+	return names
+}
+
+// Generators lists the generators called by the placeholders of a string
+// along with how many arguments each is given
+func Generators(text string) map[string][]int {
+	calls := map[string][]int{}
+	for _, match := range placeholder.FindAllStringSubmatch(text, -1) {
+		if _, isGenerator := generators[match[1]]; isGenerator || match[2] != "" {
+			calls[match[1]] = append(calls[match[1]], len(argument.FindAllString(match[2], -1)))
+		}
+	}
+	return calls
+}
+
+// resolvePlaceholder gives the value of a placeholder: a variable, or else
+// a generator called with its arguments
+func resolvePlaceholder(name string, args string, variables map[string]any) (any, bool) {
+	if args == "" {
+		if value, ok := lookupVariable(name, variables); ok { return value, true }
+	}
+
+	generator, exists := generators[name]
+	if !exists || CheckGenerator(name, len(argument.FindAllString(args, -1))) != nil { return nil, false }
+
+	// arguments are quoted strings, numbers or variables
+	values := []any{}
+	for _, arg := range argument.FindAllString(args, -1) {
+		if text, err := strconv.Unquote(arg); err == nil { values = append(values, text); continue }
+		if number, err := strconv.ParseFloat(arg, 64); err == nil { values = append(values, number); continue }
+		value, ok := lookupVariable(arg, variables)
+		if !ok { return nil, false }
+		values = append(values, value)
+	}
+
+	value, err := generator.run(values)
+	if err != nil { return nil, false }
+	return value, true
+}
+
+// isName tells if an argument of a generator refers to a variable
+func isName(arg string) bool {
+	if strings.HasPrefix(arg, `"`) { return false }
+	_, err := strconv.ParseFloat(arg, 64)
+	return err != nil
+}
+
+// lookupVariable resolves `name` or `name.nested.path` against the variables
+func lookupVariable(name string, variables map[string]any) (any, bool) {
+	if value, ok := variables[name]; ok { return value, true }
+
+	// walking into captured objects when a dotted path is used
+	parts := strings.SplitN(name, ".", 2)
+	root, ok := variables[parts[0]]
+	if !ok || len(parts) == 1 { return nil, false }
+
+	nested := gabs.Wrap(root).Path(parts[1])
+	if nested == nil { return nil, false }
+	return nested.Data(), true
+}
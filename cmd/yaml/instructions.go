@@ -1,32 +1,27 @@
 package yaml
 
 import (
-	"fmt"
-	"io"
-	"os"
-
 	"github.com/IbraheemHaseeb7/apee-i/cmd"
-	y "gopkg.in/yaml.v3"
+	"github.com/IbraheemHaseeb7/apee-i/cmd/config"
 )
 
 // Reader purpose is just to allow the programmer to make selection for YAML
-type Reader struct {}
+type Reader struct{}
 
 // ReadInstructions decodes and stores all the instructions from the configuration
-// file in the state of the program
+// file in the state of the program. Included files are merged in and the
+// result is validated first so mistakes are reported with their file, line
+// and column
 func (r *Reader) ReadInstructions(filepath string) (*cmd.Structure, error) {
-	
-	file, err := os.Open(filepath)
-	if err != nil { return &cmd.Structure{}, fmt.Errorf("Could not open file") }
 
-	defer file.Close()
+	root, err := config.Load(filepath, config.ParseYAML)
+	if err != nil { return &cmd.Structure{}, err }
+
+	if err := config.Validate(root); err != nil { return &cmd.Structure{}, err }
 
-	fileRawContents, err := io.ReadAll(file)
-	if err != nil { return &cmd.Structure{}, fmt.Errorf("Could not read file contents") }
-	
 	fileContents := new(cmd.Structure)
-	err = y.Unmarshal(fileRawContents, &fileContents)
-	if err != nil { return &cmd.Structure{}, fmt.Errorf("Could not map elements in yaml") }
+	if err := config.Decode(root, fileContents); err != nil { return &cmd.Structure{}, err }
+	config.Locate(root, fileContents)
 
 	return fileContents, nil
 }
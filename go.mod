@@ -3,14 +3,28 @@ module github.com/IbraheemHaseeb7/apee-i
 go 1.23.1
 
 require (
-	github.com/Jeffail/gabs/v2 v2.7.0 // indirect
-	github.com/jedib0t/go-pretty/v6 v6.6.5 // indirect
+	github.com/BurntSushi/toml v1.4.0
+	github.com/Jeffail/gabs/v2 v2.7.0
+	github.com/bufbuild/protocompile v0.14.1
+	github.com/jedib0t/go-pretty/v6 v6.6.5
+	github.com/spf13/cobra v1.8.1
+	go.starlark.net v0.0.0-20250623223156-8bf495bf4e9a
+	golang.org/x/net v0.34.0
+	google.golang.org/grpc v1.69.4
+	google.golang.org/protobuf v1.36.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/lint v0.0.0-20241112194109-818c5a804067 // indirect
+	golang.org/x/sync v0.10.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 	golang.org/x/tools v0.29.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20241015192408-796eee8c2d53 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
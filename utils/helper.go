@@ -1,9 +1,13 @@
 package utils
 
 import (
-	"net/http"
+	"encoding/json"
+	"fmt"
 	"os"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/IbraheemHaseeb7/apee-i/cmd"
@@ -11,7 +15,7 @@ import (
 )
 
 // ResponseLogger is used to print out the API response in a nice green or red colored table for easier read
-func ResponseLogger(structure cmd.APIStructure, res *http.Response, url string, elapsedTime time.Duration) {
+func ResponseLogger(structure cmd.APIStructure, statusCode int, url string, elapsedTime time.Duration) {
 
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
@@ -19,15 +23,31 @@ func ResponseLogger(structure cmd.APIStructure, res *http.Response, url string,
 	t.AppendSeparator()
 	expectedStatusCode := "Not Given"
 	if structure.ExpectedStatusCode == 0 {
-		if structure.Method == "POST" {
+		if structure.Method == "GRPC" {
 
-			if res.StatusCode == 201 || res.StatusCode == 200 {
+			// gRPC calls succeed with the OK code, which is 0
+			if statusCode == 0 {
+				t.SetStyle(table.StyleColoredBlackOnGreenWhite)
+			} else {
+				t.SetStyle(table.StyleColoredBlackOnRedWhite)
+			}
+		} else if structure.Method == "WEBSOCKET" {
+
+			// websockets are opened by switching protocols
+			if statusCode == 101 {
+				t.SetStyle(table.StyleColoredBlackOnGreenWhite)
+			} else {
+				t.SetStyle(table.StyleColoredBlackOnRedWhite)
+			}
+		} else if structure.Method == "POST" {
+
+			if statusCode == 201 || statusCode == 200 {
 				t.SetStyle(table.StyleColoredBlackOnGreenWhite)
 			} else {
 				t.SetStyle(table.StyleColoredBlackOnRedWhite)
 			}
 		} else {
-			if res.StatusCode == 200 {
+			if statusCode == 200 {
 				t.SetStyle(table.StyleColoredBlackOnGreenWhite)
 			} else {
 				t.SetStyle(table.StyleColoredBlackOnRedWhite)
@@ -35,18 +55,126 @@ func ResponseLogger(structure cmd.APIStructure, res *http.Response, url string,
 		}
 	} else {
 		expectedStatusCode = strconv.Itoa(structure.ExpectedStatusCode)
-		if res.StatusCode == structure.ExpectedStatusCode {
+		if statusCode == structure.ExpectedStatusCode {
 			t.SetStyle(table.StyleColoredBlackOnGreenWhite)
 		} else {
 			t.SetStyle(table.StyleColoredBlackOnRedWhite)
 		}
 	}
 
-	t.AppendRow(table.Row{structure.Method, url, strconv.Itoa(res.StatusCode), expectedStatusCode, elapsedTime.Abs().String()})
+	t.AppendRow(table.Row{structure.Method, url, strconv.Itoa(statusCode), expectedStatusCode, elapsedTime.Abs().String()})
+	t.Render()
+
+}
+
+// TimingsLogger prints the phases of a request under its response table,
+// for the verbose runs
+func TimingsLogger(timings cmd.Timings) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"DNS", "Connect", "TLS", "TTFB", "Transfer", "Total"})
+	t.AppendSeparator()
+	t.AppendRow(table.Row{timings.DNS.String(), timings.Connect.String(), timings.TLS.String(), timings.TTFB.String(), timings.Transfer.String(), timings.Total.String()})
+	t.SetStyle(table.StyleColoredBlackOnBlueWhite)
+	t.Render()
+}
+
+// SummaryLogger prints every recorded run of a step in a table, green when
+// all of them passed and red otherwise, followed by the totals
+func SummaryLogger(results []cmd.StepResult) {
+	passed, failed, skipped := 0, 0, 0
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Pipeline", "Row", "Step", "Result", "Time Lapsed"})
+	t.AppendSeparator()
+	for _, result := range results {
+		outcome := "passed"
+		switch {
+		case result.Err != nil: outcome = "failed"; failed++
+		case result.Skipped: outcome = "skipped"; skipped++
+		default: passed++
+		}
+		t.AppendRow(table.Row{result.Pipeline, result.Iteration, result.Step, outcome, result.Duration.String()})
+	}
+
+	if failed == 0 { t.SetStyle(table.StyleColoredBlackOnGreenWhite) } else { t.SetStyle(table.StyleColoredBlackOnRedWhite) }
+	fmt.Println()
+	t.Render()
+
+	color := Green
+	if failed > 0 { color = Red }
+	fmt.Printf(color + "%d passed, %d failed, %d skipped\n" + Reset, passed, failed, skipped)
+}
+
+// RegressionLogger prints the steps slower than their baseline in a red
+// table, or tells that none is
+func RegressionLogger(regressions []cmd.Regression) {
+	if len(regressions) == 0 { fmt.Println(Green + "No step regressed against its baseline" + Reset); return }
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Pipeline", "Row", "Step", "Baseline", "Now", "Slowdown"})
+	t.AppendSeparator()
+	for _, regression := range regressions {
+		now := regression.Entry.Latency()
+		slowdown := fmt.Sprintf("+%s", now - regression.Baseline)
+		if regression.Baseline > 0 { slowdown += fmt.Sprintf(" (+%.0f%%)", float64(now - regression.Baseline) / float64(regression.Baseline) * 100) }
+		t.AppendRow(table.Row{regression.Entry.Pipeline, regression.Entry.Iteration, regression.Entry.Step, regression.Baseline.String(), now.String(), slowdown})
+	}
+	t.SetStyle(table.StyleColoredBlackOnRedWhite)
+	fmt.Println()
 	t.Render()
+}
+
+// ValidateExpectedBody compares the response body with the expected body
+// of a step. Objects only need the expected keys, so a response may carry
+// more fields than the ones written, arrays and values have to be equal
+func ValidateExpectedBody(expected any, actual any) error {
+	mismatches := bodyMismatches(expected, actual, "body")
+	if len(mismatches) == 0 { return nil }
+	return fmt.Errorf("Response body does not match expectedBody:\n  %s", strings.Join(mismatches, "\n  "))
+}
+
+// bodyMismatches walks the expected value and lists where the actual one differs
+func bodyMismatches(expected any, actual any, path string) []string {
+	switch want := expected.(type) {
+	case map[string]any:
+		got, ok := actual.(map[string]any)
+		if !ok { return []string{fmt.Sprintf("%s: expected an object, got %s", path, jsonOf(actual))} }
+
+		mismatches := []string{}
+		keys := make([]string, 0, len(want))
+		for key := range want { keys = append(keys, key) }
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, exists := got[key]
+			if !exists { mismatches = append(mismatches, fmt.Sprintf("%s.%s: missing", path, key)); continue }
+			mismatches = append(mismatches, bodyMismatches(want[key], value, path + "." + key)...)
+		}
+		return mismatches
 
+	case []any:
+		got, ok := actual.([]any)
+		if !ok { return []string{fmt.Sprintf("%s: expected an array, got %s", path, jsonOf(actual))} }
+		if len(got) != len(want) { return []string{fmt.Sprintf("%s: expected %d items, got %d", path, len(want), len(got))} }
+
+		mismatches := []string{}
+		for i := range want {
+			mismatches = append(mismatches, bodyMismatches(want[i], got[i], fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return mismatches
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, jsonOf(expected), jsonOf(actual))}
+	}
+	return nil
 }
 
-// func ValidateExpectedBody() {
-//
-// }
+// jsonOf writes a value the way it appears in a response
+func jsonOf(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil { return fmt.Sprint(value) }
+	return string(data)
+}